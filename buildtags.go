@@ -0,0 +1,102 @@
+package main
+
+import (
+	"go/build/constraint"
+	"path/filepath"
+	"strings"
+)
+
+// knownOS and knownArch hold the values of GOOS
+// and GOARCH that the go command recognises in
+// file names.
+var (
+	knownOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true,
+		"freebsd": true, "hurd": true, "illumos": true, "ios": true,
+		"js": true, "linux": true, "nacl": true, "netbsd": true,
+		"openbsd": true, "plan9": true, "solaris": true, "wasip1": true,
+		"windows": true, "zos": true,
+	}
+
+	knownArch = map[string]bool{
+		"386": true, "amd64": true, "amd64p32": true, "arm": true,
+		"armbe": true, "arm64": true, "arm64be": true, "loong64": true,
+		"mips": true, "mipsle": true, "mips64": true, "mips64le": true,
+		"mips64p32": true, "mips64p32le": true, "ppc": true, "ppc64": true,
+		"ppc64le": true, "riscv": true, "riscv64": true, "s390": true,
+		"s390x": true, "sparc": true, "sparc64": true, "wasm": true,
+	}
+)
+
+// fileTags returns the build constraint implied by
+// the _GOOS, _GOARCH or _GOOS_GOARCH suffix of the
+// file with the given name, ignoring any extension,
+// as the go command does for source files, or nil
+// if it has none. So logo_linux_amd64.png gives
+// linux && amd64.
+func fileTags(name string) constraint.Expr {
+	name = filepath.Base(name)
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[:i]
+	}
+
+	// The element before the first underscore is
+	// never a suffix, so a file named linux.png
+	// isn't constrained.
+
+	i := strings.Index(name, "_")
+	if i < 0 {
+		return nil
+	}
+
+	l := strings.Split(name[i:], "_")
+	if n := len(l); n > 0 && l[n-1] == "test" {
+		l = l[:n-1]
+	}
+
+	n := len(l)
+	switch {
+	case n >= 2 && knownOS[l[n-2]] && knownArch[l[n-1]]:
+		return &constraint.AndExpr{X: &constraint.TagExpr{Tag: l[n-2]}, Y: &constraint.TagExpr{Tag: l[n-1]}}
+	case n >= 1 && (knownOS[l[n-1]] || knownArch[l[n-1]]):
+		return &constraint.TagExpr{Tag: l[n-1]}
+	}
+
+	return nil
+}
+
+// withFileTags returns tags, the constraint given
+// with -tags, combined with the one implied by the
+// named file's suffixes, unless -no-auto-tags is
+// set.
+func withFileTags(tags constraint.Expr, name string) constraint.Expr {
+	auto := fileTags(name)
+	switch {
+	case *noAutoTags || auto == nil:
+		return tags
+	case tags == nil:
+		return auto
+	}
+
+	return &constraint.AndExpr{X: tags, Y: auto}
+}
+
+// untagged returns name, unless -no-auto-tags is
+// set and it has suffixes that fileTags would
+// constrain it by, in which case the underscore
+// before the last of them is replaced by a dash.
+// The go command constrains files by their names
+// too, so this is the only way to avoid it.
+func untagged(name string) string {
+	if !*noAutoTags || fileTags(name) == nil {
+		return name
+	}
+
+	base := name
+	if i := strings.Index(base, "."); i >= 0 {
+		base = base[:i]
+	}
+
+	i := strings.LastIndex(base, "_")
+	return name[:i] + "-" + name[i+1:]
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/SlyMarbo/embed/embed"
+)
+
+// CacheFile is the file in the working directory
+// holding the declarations embedded by earlier
+// runs, unless disabled with -no-cache.
+const CacheFile = ".embedcache"
+
+// uncached holds the flags that don't change the
+// declarations embedded for each file, so that
+// changing them doesn't invalidate the cache.
+var uncached = map[string]bool{
+	"o": true, "outdir": true, "n": true, "dry-run": true, "verify": true,
+	"v": true, "q": true, "progress": true, "j": true, "force": true,
+	"strict": true, "manifest": true, "list": true, "sort": true, "no-cache": true,
+}
+
+// cacheKey returns the key for caches made by this
+// build of embed with these flags, or the empty
+// string if the build can't be identified.
+func cacheKey() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+
+	info, err := os.Stat(exe)
+	if err != nil {
+		return ""
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%d %d\n", info.Size(), info.ModTime().UnixNano())
+	flag.VisitAll(func(f *flag.Flag) {
+		if !uncached[f.Name] {
+			fmt.Fprintf(h, "%s=%q\n", f.Name, f.Value)
+		}
+	})
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// loadCache returns the cache read from CacheFile,
+// or nil if it's disabled with -no-cache or can't
+// be used with the other flags. A missing or
+// outdated cache gives an empty one.
+func loadCache() *embed.Cache {
+	if *noCache || *blob || *bundle || *goEmbed != "" {
+		return nil
+	}
+
+	key := cacheKey()
+	if key == "" {
+		return nil
+	}
+
+	data, _ := os.ReadFile(CacheFile)
+	return embed.DecodeCache(data, key)
+}
+
+// saveCache writes cache to CacheFile, unless
+// it's nil or nothing is being written.
+func saveCache(cache *embed.Cache) {
+	if cache == nil || *dryRun || *verify {
+		return
+	}
+
+	data, err := cache.Encode()
+	if err == nil {
+		err = writeData(CacheFile, data)
+	}
+
+	if err != nil {
+		errorf("failed to write cache: %v", err)
+	}
+}
@@ -2,277 +2,1426 @@
 //
 // Embed reads the input files and writes their contents as embedded data
 // in one Go file per input file, by appending .go to the filename.
+// With -outdir, those files are written to the given directory instead,
+// named after the input's path so that they don't collide.
 // Specifying -o overrides this by writing all files to a single output
 // with the given name. Embed attempts to detect the package name but
-// it can be specified with -package.
+// it can be specified with -package. When the output directory has no Go
+// files yet, the package is named after the directory, as the go command
+// would name it. Directories are walked and every regular file in them
+// is embedded.
 //
-// 	$ embed -o content.go -gzip -sha1 content/index.html content/style.css
+// Each file's own output is constrained by any _GOOS, _GOARCH or
+// _GOOS_GOARCH suffix in its name, as the go command treats source files,
+// so logo_linux_amd64.png gets //go:build linux && amd64, combined with
+// any -tags. The go command would constrain logo_linux_amd64.png.go by
+// its name anyway, so -no-auto-tags drops the constraint by naming the
+// output logo_linux-amd64.png.go instead.
 //
+// With -group pkg=file1,file2, the files are embedded in their own package
+// instead, written to -o with {pkg} replaced by the package's name, so one
+// command can generate several packages:
+//
+//	embed -o gen/{pkg}/assets.go -group css=a.css,b.css -group img=img
+//
+// Each -group gives another package, and files given for the same package
+// more than once are combined.
+//
+// With -rename old=new, the file at old is embedded under the logical
+// name new, which is used for its identifiers and as its key in -map, -fs
+// and the like, so build/output/app.min.js can be served as app.js. It may
+// be repeated, and the logical names must be unique.
+//
+// Arguments may also be glob patterns, which are expanded by embed
+// itself, and where ** matches any number of directories. Files given
+// more than once, such as by overlapping patterns, are only embedded
+// once, or reported with -strict.
+//
+// With -max-file-size N, files larger than N bytes are skipped and
+// reported, without being read, so a mistaken pattern can't embed a huge
+// log file. Standard input fails once more than N bytes have been read.
+//
+// Identifiers are derived from each file's name, with any characters that
+// aren't valid in identifiers replaced by underscores, so café.txt embeds
+// as café_txt. With -ascii-names, non-ASCII letters are transliterated
+// first, giving cafe_txt. Letters with no ASCII form, such as CJK, are
+// written as their code points, so 日本.txt embeds as u65e5u672c_txt.
+//
+// Byte slices are written -width bytes to a line, each as two hex digits
+// followed by a comma, the last included, so the columns line up and
+// changing a byte only changes its own line in a diff.
+//
+// With -pretty N, byte slices are split into blocks of N lines, like a
+// hex dump, each after a blank line and a comment giving the offset of its
+// first byte, such as // 0x0180, which helps when reviewing binary files in
+// diffs. It makes the output larger, so it's off by default.
+//
+// With -raw, text files are embedded as raw string literals. Files that
+// can't be written that way, because they contain backticks, carriage
+// returns, control characters or invalid UTF-8, are embedded as byte
+// slices as usual.
+//
+// With -lines, text files are embedded as slices of their lines, without
+// the newlines. A file that doesn't end in a newline gives the same lines
+// as one that does, but its _Bytes function still returns the original.
+//
+// With -chunk-string N, string literals from -string, -base64 or -ascii85
+// longer than N bytes are split into constants X_0, X_1 and so on, with X
+// declared as their concatenation. The compiler handles many short
+// literals far faster than one huge one. Escape sequences are never split.
+//
+// With -gzip, each file stored compressed also gets _Size and _GzipSize
+// constants, giving its size before and after compression. Its _gz data
+// can then be served as it is, with a Content-Encoding of gzip, rather
+// than being decompressed to be compressed again. With -base64 or
+// -ascii85, there is no _gz data, so these constants aren't added.
+//
+// With compression and -fs, the file system holds each file compressed,
+// and only decompresses it when it's first opened or read, rather than
+// every file when the program starts. Its FileInfo still gives the size of
+// the uncompressed contents.
+//
+// With -modtime, the files in -fs and -httpfs also report their
+// modification times, so http.FileServer sends Last-Modified headers.
+//
+// With -copy-accessor, each file's data is unexported and only returned by
+// a function of the file's name, which returns a fresh copy each time, so
+// callers can't modify the data shared by everyone else. Each call
+// allocates and copies the whole file, so callers that only read the data
+// should keep the copy rather than calling it repeatedly.
+//
+// With -funcs, each file's data is declared as a function returning a
+// fresh []byte, rather than as a variable, so the linker drops the files
+// that are never called. Current linkers usually drop unreferenced
+// variables too, so this mostly matters for older toolchains, and for
+// guaranteeing it. Each call allocates and copies the whole file, so
+// callers should keep the result rather than calling it repeatedly. Files
+// in a -map, -fs or the like are always kept, as they're all referenced.
+//
+// With -bytes-reader, each file also gets a _BytesReader function
+// returning a *bytes.Reader positioned at the start of its contents. Unlike
+// the io.Reader from -reader, it can Seek and ReadAt, so it can serve HTTP
+// range requests with http.ServeContent. Compressed data is decompressed
+// first, so the function also returns an error.
+//
+// With -transcode from:to, text files are converted from one encoding to
+// another before they're embedded, so the size and hashes describe the
+// converted text. As with -normalize-eol, text files are those with common
+// text extensions or those given by -text, and other files are left
+// alone. The encodings are utf-8, utf-16 (in the byte order given by its
+// byte order mark, or big-endian), utf-16le, utf-16be and iso-8859-1,
+// also known as latin1. Byte order marks are dropped, except that utf-16
+// output starts with one.
+//
+// With -verify, the outputs are generated as usual but compared with the
+// existing files rather than written, exiting with an error that
+// summarises each difference if any are out of date. This checks in CI
+// that generated files were regenerated after their inputs changed.
+//
+// The declarations embedded for each file are cached in .embedcache, in
+// the working directory, keyed by the file's contents and the flags given,
+// so unchanged files are copied from the cache rather than compressed and
+// encoded again. Files are only copied if that gives exactly the output
+// embedding them again would, and the cache only keeps the files embedded
+// by the last run. It isn't used with -blob, -bundle or -use-goembed, and
+// -no-cache disables it. The cache needn't be checked in.
+//
+// With -gentest, each output also gets a _test.go file checking that the
+// embedded data still matches its hashes, with a test named after each
+// file's identifier and hash, such as TestLogo_pngSHA1 for logo.png.
+//
+// With -manifest file, a manifest listing each file's path and its hash,
+// by the first algorithm given with -hash, -sha1 or -sha256, is also
+// written to file, in the format of sha256sum, so the files can be checked
+// with sha256sum -c or the like independently of the Go code. Like the
+// output, it's written atomically. With -normalize-eol or -transcode, the
+// hashes describe the converted contents, which may not match the files.
+// It can't be used with -append, as it would only list the files added.
+//
+// With -line-directives, each variable is preceded by a //line directive
+// naming the file it was embedded from, so stack traces, coverage and
+// compiler errors in the generated declarations point at the original
+// file rather than the generated one.
+//
+// With -use-goembed dir, the files are copied to dir, next to the output,
+// and embedded with go:embed directives rather than as byte slices, so the
+// compiler reads them directly. Each file still gets its variable,
+// declared as a []byte, along with its hashes, metadata and any -map, -fs
+// or -struct, and an embed.FS named after the directory, such as StaticFS
+// for static, holds them all under their paths in dir. Files removed from
+// the inputs aren't removed from dir.
+//
+// With -register Func, each output also gets an init function calling
+// Func(path, data) for every file it embeds, so that several outputs
+// compiled together can populate a single registry. Func isn't generated,
+// so the package must provide it, with the signature func(string, []byte).
+//
+// With -bundle, every file is written to a single tar archive, which is
+// compressed with gzip as a whole. Many similar files compress far better
+// together than alone. Each file's accessor extracts it from the archive,
+// which is only decompressed once, and AssetNames lists the files in it.
+//
+// The code generation itself is implemented by the package
+// github.com/SlyMarbo/embed/embed, which other generators can use
+// directly, with the options set by the flags given in an Options.
+//
+//	$ embed -o content.go -gzip -sha1 content/index.html content/style.css
 package main
 
 import (
 	"bytes"
-	"compress/gzip"
-	"crypto/sha1"
+	"compress/flate"
+	"errors"
 	"flag"
 	"fmt"
 	"go/build"
-	"hash"
+	"go/build/constraint"
+	"go/token"
 	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"unicode"
 	"unicode/utf8"
+
+	"github.com/SlyMarbo/embed/embed"
 )
 
+func init() {
+	flag.BoolVar(dryRun, "dry-run", false, "Same as -n")
+}
+
 func usage() {
 	app := filepath.Base(os.Args[0])
-	fmt.Fprintf(os.Stderr, "Usage:\n  %s [OPTIONS] FILE...\n\n", app)
+	fmt.Fprintf(os.Stderr, "Usage:\n  %s [OPTIONS] FILE|DIR...\n\n", app)
 	fmt.Fprintf(os.Stderr, "By default %s reads the input files and writes their\n", app)
 	fmt.Fprintf(os.Stderr, "contents as embedded data in one Go file per input\n")
 	fmt.Fprintf(os.Stderr, "file, by appending .go to the filename. Specifying -o\n")
 	fmt.Fprintf(os.Stderr, "overrides this by writing all files to a single output\n")
 	fmt.Fprintf(os.Stderr, "with the given name. %s attempts to detect the package\n", app)
-	fmt.Fprintf(os.Stderr, "name but it can be specified with -package.\n\n")
+	fmt.Fprintf(os.Stderr, "name but it can be specified with -package. Directories\n")
+	fmt.Fprintf(os.Stderr, "are walked and every regular file in them is embedded.\n\n")
 	flag.PrintDefaults()
 	os.Exit(2)
 }
 
 var (
-	pkg      = flag.String("package", "", "Package name in output file(s)")
-	output   = flag.String("o", "", "Output all data to this file")
-	compress = flag.Bool("gzip", false, "Compress data with gzip before embedding")
-	sha      = flag.Bool("sha1", false, "Also embed SHA1 hash of data")
+	pkg            = flag.String("package", "", "Package name in output file(s)")
+	output         = flag.String("o", "", "Output all data to this file, or - for standard output")
+	gzipFlag       = flag.Bool("gzip", false, "Compress data with gzip before embedding (same as -compress=gzip)")
+	compressFlag   = flag.String("compress", "none", "Compress data before embedding with gzip, zlib, flate or none")
+	smartCompress  = flag.Bool("smart-compress", false, "Store files that compression wouldn't shrink, such as images, uncompressed")
+	level          = flag.Int("level", flate.BestCompression, "Compression level, from 0 (none) through 1 (fastest) to 9 (smallest), or -1 for the default")
+	sha            = flag.Bool("sha1", false, "Also embed SHA1 hash of data")
+	sha2           = flag.Bool("sha256", false, "Also embed SHA256 hash of data")
+	hashFirst      = flag.Bool("hash-first", false, "Write hashes before the data rather than after it")
+	crc            = flag.Bool("crc32", false, "Also embed CRC-32 checksum of data")
+	hashList       = flag.String("hash", "", "Also embed hashes of data using these comma-separated algorithms")
+	hashFormat     = flag.String("hashformat", "bytes", "Embed hashes as \"bytes\" or \"hex\" strings")
+	str            = flag.Bool("string", false, "Embed data as a string literal")
+	b64            = flag.Bool("base64", false, "Embed data as a base64 constant with a decoding function")
+	a85            = flag.Bool("ascii85", false, "Embed data as an ascii85 constant with a decoding function")
+	gofmt          = flag.Bool("gofmt", true, "Format output with gofmt")
+	followSymlinks = flag.Bool("follow-symlinks", false, "Follow symlinks when walking directories, rather than skipping them")
+	recurse        = flag.Bool("recursive", true, "Embed the contents of directories recursively")
+	index          = flag.String("map", "", "Also write a map with this name from path to data")
+	assets         = flag.Bool("assets", false, "Also write Asset and AssetNames functions using the map from -map")
+	hashedNames    = flag.Bool("hashed-names", false, "Also write maps between each path in -map and one including a hash of its contents")
+	register       = flag.String("register", "", "Also write an init function passing each file's path and data to this function")
+	structName     = flag.String("struct", "", "Also write a struct with this name with a field for each file")
+	nested         = flag.Bool("nested", false, "Nest the struct from -struct by directory, mirroring the file tree")
+	fsName         = flag.String("fs", "", "Also write an fs.FS with this name holding the files")
+	httpFSName     = flag.String("httpfs", "", "Also write an http.FileSystem with this name serving the files")
+	modtime        = flag.Bool("modtime", false, "Also embed modification time of data")
+	modeFlag       = flag.Bool("mode", false, "Also embed permission bits of data")
+	sizes          = flag.Bool("size", false, "Also embed size of data before compression")
+	force          = flag.Bool("force", false, "Rewrite outputs even if unchanged, and overwrite files that weren't generated")
+	jobs           = flag.Int("j", 0, "Number of files to embed concurrently without -o (default GOMAXPROCS)")
+	appendOutput   = flag.Bool("append", false, "Append to the output file given with -o, if it exists")
+	headerFlag     = flag.String("header", "", "File containing a comment to write at the top of output file(s), or the comment itself")
+	marker         = flag.String("marker", embed.DefaultMarker, "Comment marking output file(s) as generated")
+	tags           = flag.String("tags", "", "Build constraint for output file(s), such as \"linux && amd64\"")
+	noAutoTags     = flag.Bool("no-auto-tags", false, "Without -o, don't constrain the output for a file like logo_linux_amd64.png to linux && amd64, by naming it logo_linux-amd64.png.go")
+	generate       = flag.Bool("generate", false, "Also write a go:generate directive repeating this command")
+	genTest        = flag.Bool("gentest", false, "Also write a test verifying the embedded hashes")
+	manifest       = flag.String("manifest", "", "Also write a manifest of each file's path and hash, in the format of sha256sum, to this file")
+	chunk          = flag.Int("chunk", 0, "Split byte slices larger than this many bytes into several variables (0 never splits)")
+	chunkString    = flag.Int("chunk-string", 0, "Split string literals larger than this many bytes into several constants (0 never splits)")
+	mimeType       = flag.Bool("mime", false, "Also embed content type of data")
+	info           = flag.Bool("info", false, "Embed the metadata from -size, -modtime, -mode and -mime in a FileInfo for each file")
+	width          = flag.Int("width", embed.DefaultWidth, "Number of bytes per line in byte slices")
+	pretty         = flag.Int("pretty", 0, "Split byte slices into blocks of this many lines, each after a blank line and a comment giving its offset, like a hex dump (0 never splits)")
+	list           = flag.String("list", "", "Also embed the files listed in this file, one per line")
+	stdinName      = flag.String("name", "", "Name of the data read from standard input, or else the identifier for the only file, or for each file with -map")
+	prefix         = flag.String("prefix", "", "Prefix added to each identifier")
+	trim           = flag.String("trimprefix", "", "Prefix removed from each name before deriving identifiers")
+	export         = flag.Bool("export", false, "Export the generated identifiers")
+	asciiNames     = flag.Bool("ascii-names", false, "Transliterate non-ASCII letters in identifiers to ASCII, such as cafe_txt for café.txt")
+	outdir         = flag.String("outdir", "", "Directory to write per-file outputs to, named after each file's path")
+	reader         = flag.Bool("reader", false, "Also write a function returning a reader for each file's contents")
+	bytesReader    = flag.Bool("bytes-reader", false, "Also write a function returning a *bytes.Reader for each file's contents, which can Seek and ReadAt")
+	copyAccessor   = flag.Bool("copy-accessor", false, "Return each file's data as a fresh copy from a function, so it can't be modified, at the cost of an allocation per call")
+	funcs          = flag.Bool("funcs", false, "Declare each file's data as a function returning it, so the linker can drop the files that are never used")
+	goEmbed        = flag.String("use-goembed", "", "Copy the files to this directory, next to -o, and embed them with go:embed rather than as byte slices")
+	verbose        = flag.Bool("v", false, "Print the size of each file before and after compression")
+	sortInputs     = flag.Bool("sort", false, "Embed files sorted by path, rather than in the order given")
+	blob           = flag.Bool("blob", false, "Embed every file in a single byte slice, looked up with Get")
+	bundle         = flag.Bool("bundle", false, "Embed every file in a single tar archive, compressed as a whole with gzip")
+	docs           = flag.Bool("docs", false, "Write a doc comment describing each embedded variable")
+	noComments     = flag.Bool("no-comments", false, "Omit the comment naming the file before each variable")
+	lineDirectives = flag.Bool("line-directives", false, "Write a //line directive before each variable, attributing it to the original file")
+	array          = flag.Bool("array", false, "Embed data as fixed-size byte arrays, which can be sliced with [:]")
+	normalizeEOL   = flag.Bool("normalize-eol", false, "Convert CRLF line endings to LF in text files")
+	text           = flag.String("text", "", "Comma-separated extensions of additional text files for -normalize-eol and -transcode")
+	transcode      = flag.String("transcode", "", "Convert text between these encodings before embedding, given as from:to, such as utf-16le:utf-8")
+	lines          = flag.Bool("lines", false, "Embed text as a slice of its lines")
+	constant       = flag.Bool("const", false, "Declare string data as constants rather than variables")
+	showProgress   = flag.Bool("progress", false, "Print the progress of large embeds")
+	quiet          = flag.Bool("q", false, "Print only errors, overriding -v and -progress")
+	minCompress    = flag.Int64("min-compress-size", 0, "Store files smaller than this many bytes uncompressed")
+	maxFileSize    = flag.Int64("max-file-size", 0, "Skip files larger than this many bytes, reporting them as failures (0 is unlimited)")
+	dryRun         = flag.Bool("n", false, "Print the outputs and identifiers that would be written, without writing them")
+	verify         = flag.Bool("verify", false, "Check that the outputs are up to date with their inputs, without writing them")
+	strict         = flag.Bool("strict", false, "Stop at the first file that can't be embedded")
+	noCache        = flag.Bool("no-cache", false, "Embed every file again, rather than copying unchanged files from "+CacheFile)
+	raw            = flag.Bool("raw", false, "Embed text as a raw string literal where possible")
 )
 
 func main() {
 	flag.Parse()
-	args := flag.Args()
-	if len(args) == 0 {
+	if *quiet {
+		*verbose = false
+		*showProgress = false
+	}
+
+	if flag.NArg() == 0 && *list == "" && len(groups) == 0 {
 		usage()
 	}
 
-	// Package name
+	// The options that need parsing are set as
+	// they're checked, and the rest once they
+	// all have been.
+
+	var opts embed.Options
+	var err error
+	if opts.Compression, err = parseCompression(); err != nil {
+		errorf("invalid -compress: %v", err)
+		os.Exit(2)
+	}
+
+	if *level < flate.DefaultCompression || *level > flate.BestCompression {
+		errorf("invalid -level: must be -1 for the default, or from 0 to 9")
+		os.Exit(2)
+	}
+
+	if *smartCompress && opts.Compression == nil {
+		errorf("-smart-compress requires -gzip or -compress")
+		os.Exit(2)
+	}
+
+	if *pretty < 0 {
+		errorf("invalid -pretty: must not be negative")
+		os.Exit(2)
+	}
+
+	if *maxFileSize < 0 {
+		errorf("invalid -max-file-size: must not be negative")
+		os.Exit(2)
+	}
+
+	if *minCompress < 0 {
+		errorf("invalid -min-compress-size: must not be negative")
+		os.Exit(2)
+	}
+
+	if *minCompress > 0 && opts.Compression == nil {
+		errorf("-min-compress-size requires -gzip or -compress")
+		os.Exit(2)
+	}
+
+	if *b64 && *a85 {
+		errorf("only one of -base64 and -ascii85 may be used")
+		os.Exit(2)
+	}
+
+	if *b64 {
+		opts.Encoding = embed.Encodings["base64"]
+	} else if *a85 {
+		opts.Encoding = embed.Encodings["ascii85"]
+	}
+
+	if *str && opts.Encoding != nil {
+		errorf("only one of -string, -base64 and -ascii85 may be used")
+		os.Exit(2)
+	}
+
+	if *width < 1 {
+		errorf("invalid -width: must be at least 1")
+		os.Exit(2)
+	}
+
+	if *chunk < 0 {
+		errorf("invalid -chunk: must not be negative")
+		os.Exit(2)
+	}
+
+	if *chunk > 0 && (*str || opts.Encoding != nil) {
+		errorf("-chunk cannot be used with -string, -base64 or -ascii85")
+		os.Exit(2)
+	}
+
+	if *chunkString < 0 {
+		errorf("invalid -chunk-string: must not be negative")
+		os.Exit(2)
+	}
+
+	if *chunkString > 0 && !*str && opts.Encoding == nil {
+		errorf("-chunk-string requires -string, -base64 or -ascii85")
+		os.Exit(2)
+	}
+
+	if *raw && (*str || opts.Encoding != nil || opts.Compression != nil) {
+		errorf("-raw cannot be used with -string, -base64, -ascii85 or compression")
+		os.Exit(2)
+	}
+
+	if *outdir != "" && *output != "" {
+		errorf("-outdir cannot be used with -o")
+		os.Exit(2)
+	}
+
+	if *blob {
+		if *output == "" {
+			errorf("-blob requires -o")
+			os.Exit(2)
+		}
+
+		if *str || opts.Encoding != nil || *raw || *chunk > 0 || opts.Compression != nil || *reader || *appendOutput {
+			errorf("-blob cannot be used with -string, -base64, -ascii85, -raw, -chunk, compression, -reader or -append")
+			os.Exit(2)
+		}
+	}
+
+	if *docs && *noComments {
+		errorf("-docs cannot be used with -no-comments")
+		os.Exit(2)
+	}
+
+	if *info && *output == "" {
+		errorf("-info requires -o")
+		os.Exit(2)
+	}
+
+	if *bundle {
+		if *output == "" {
+			errorf("-bundle requires -o")
+			os.Exit(2)
+		}
+
+		if *str || opts.Encoding != nil || *raw || *chunk > 0 || *lines || *array || *blob || opts.Compression != nil || *reader || *appendOutput || *assets {
+			errorf("-bundle cannot be used with -string, -base64, -ascii85, -raw, -chunk, -lines, -array, -blob, compression, -reader, -append or -assets")
+			os.Exit(2)
+		}
+	}
+
+	if *lines && (*str || opts.Encoding != nil || *raw || *chunk > 0 || *blob || *array || opts.Compression != nil || *reader) {
+		errorf("-lines cannot be used with -string, -base64, -ascii85, -raw, -chunk, -blob, -array, compression or -reader")
+		os.Exit(2)
+	}
+
+	if *constant && !*str && !*raw {
+		errorf("-const requires -string or -raw, as only strings can be constants")
+		os.Exit(2)
+	}
+
+	if *array && (*str || opts.Encoding != nil || *raw || *chunk > 0 || *blob) {
+		errorf("-array cannot be used with -string, -base64, -ascii85, -raw, -chunk or -blob")
+		os.Exit(2)
+	}
+
+	if *appendOutput {
+		if *output == "" || *output == "-" {
+			errorf("-append requires -o with a file")
+			os.Exit(2)
+		}
+
+		if *index != "" || *structName != "" || *fsName != "" || *httpFSName != "" || *genTest || *manifest != "" {
+			errorf("-append cannot be used with -map, -struct, -fs, -httpfs, -gentest or -manifest")
+			os.Exit(2)
+		}
+	}
+
+	if *verify && (*dryRun || *appendOutput || *output == "-") {
+		errorf("-verify cannot be used with -n, -append or -o -")
+		os.Exit(2)
+	}
+
+	if *genTest && *output == "-" {
+		errorf("-gentest cannot be used with -o -")
+		os.Exit(2)
+	}
+
+	if *pretty > 0 && (*str || opts.Encoding != nil || *lines || *goEmbed != "") {
+		errorf("-pretty cannot be used with -string, -base64, -ascii85, -lines or -use-goembed")
+		os.Exit(2)
+	}
+
+	if *copyAccessor && (*str || opts.Encoding != nil || *raw || *lines || *blob || *bundle) {
+		errorf("-copy-accessor cannot be used with -string, -base64, -ascii85, -raw, -lines, -blob or -bundle")
+		os.Exit(2)
+	}
+
+	if *funcs && (*str || opts.Encoding != nil || *raw || *lines || *array || *chunk > 0 || *blob || *bundle || *copyAccessor || *goEmbed != "") {
+		errorf("-funcs cannot be used with -string, -base64, -ascii85, -raw, -lines, -array, -chunk, -blob, -bundle, -copy-accessor or -use-goembed")
+		os.Exit(2)
+	}
+
+	if *goEmbed != "" {
+		if *output == "" || *output == "-" {
+			errorf("-use-goembed requires -o with a file")
+			os.Exit(2)
+		}
+
+		dir := path.Clean(filepath.ToSlash(*goEmbed))
+		if !fs.ValidPath(dir) || dir == "." {
+			errorf("invalid -use-goembed: %q must be a subdirectory of the output's directory", *goEmbed)
+			os.Exit(2)
+		}
+
+		if *str || opts.Encoding != nil || *raw || *lines || *array || *chunk > 0 || *blob || *bundle || opts.Compression != nil || *copyAccessor || *appendOutput {
+			errorf("-use-goembed cannot be used with -string, -base64, -ascii85, -raw, -lines, -array, -chunk, -blob, -bundle, compression, -copy-accessor or -append")
+			os.Exit(2)
+		}
+
+		*goEmbed = dir
+	}
+
+	if *hashFormat != "bytes" && *hashFormat != "hex" {
+		errorf("invalid -hashformat: must be bytes or hex")
+		os.Exit(2)
+	}
+
+	if strings.ContainsAny(*marker, "\r\n") {
+		errorf("invalid -marker: must be a single line")
+		os.Exit(2)
+	}
+
+	if !strings.HasPrefix(*marker, "//") {
+		*marker = "// " + *marker
+	}
+
+	if *headerFlag != "" {
+		if opts.Header, err = parseHeader(); err != nil {
+			errorf("failed to read header: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	if *tags != "" {
+		if opts.Tags, err = parseTags(); err != nil {
+			errorf("invalid -tags: %v", err)
+			os.Exit(2)
+		}
+	}
+
+	if *transcode != "" {
+		if opts.TranscodeFrom, opts.TranscodeTo, err = parseTranscode(); err != nil {
+			errorf("invalid -transcode: %v", err)
+			os.Exit(2)
+		}
+	}
+
+	if opts.Hashes, err = parseHashes(); err != nil {
+		errorf("invalid -hash: %v", err)
+		os.Exit(2)
+	}
+
+	if *genTest && len(opts.Hashes) == 0 && !*crc {
+		errorf("-gentest requires a hash, such as -sha1")
+		os.Exit(2)
+	}
+
+	if *index != "" {
+		if *output == "" {
+			errorf("-map requires -o")
+			os.Exit(2)
+		}
+	}
+
+	if *assets && *index == "" {
+		errorf("-assets requires -map")
+		os.Exit(2)
+	}
+
+	if *hashedNames {
+		if *index == "" {
+			errorf("-hashed-names requires -map")
+			os.Exit(2)
+		}
+
+		if len(opts.Hashes) == 0 {
+			errorf("-hashed-names requires a hash, such as -sha256")
+			os.Exit(2)
+		}
+	}
+
+	if *register != "" && !token.IsIdentifier(*register) {
+		errorf("invalid -register: %q is not a valid identifier", *register)
+		os.Exit(2)
+	}
+
+	if *structName != "" {
+		if *output == "" {
+			errorf("-struct requires -o")
+			os.Exit(2)
+		}
+	}
+
+	if *nested && *structName == "" {
+		errorf("-nested requires -struct")
+		os.Exit(2)
+	}
+
+	if *fsName != "" {
+		if *output == "" {
+			errorf("-fs requires -o")
+			os.Exit(2)
+		}
+	}
+
+	if *httpFSName != "" {
+		if *output == "" {
+			errorf("-httpfs requires -o")
+			os.Exit(2)
+		}
+	}
+
+	if len(groups) > 0 {
+		if !strings.Contains(*output, PackagePlaceholder) {
+			errorf("-group requires -o containing %s, which is replaced by each package", PackagePlaceholder)
+			os.Exit(2)
+		}
+
+		if *pkg != "" {
+			errorf("-group cannot be used with -package")
+			os.Exit(2)
+		}
+
+		if flag.NArg() > 0 || *list != "" {
+			errorf("-group cannot be used with -list or files given as arguments")
+			os.Exit(2)
+		}
+	} else if strings.Contains(*output, PackagePlaceholder) {
+		errorf("-o containing %s requires -group", PackagePlaceholder)
+		os.Exit(2)
+	}
+
+	if *manifest != "" {
+		if *output == "" {
+			errorf("-manifest requires -o")
+			os.Exit(2)
+		}
+
+		if len(opts.Hashes) == 0 {
+			errorf("-manifest requires a hash, such as -sha256")
+			os.Exit(2)
+		}
+
+		if len(groups) > 0 && !strings.Contains(*manifest, PackagePlaceholder) {
+			errorf("-manifest with -group must contain %s, which is replaced by each package", PackagePlaceholder)
+			os.Exit(2)
+		}
+	}
+
+	// Package name, unless each group is given
+	// its own.
 
 	if *pkg != "" {
-		*pkg = sanitise(*pkg)
-	} else {
+		name, err := packageName(*pkg)
+		if err != nil {
+			errorf("invalid -package: %v", err)
+			os.Exit(2)
+		}
+
+		*pkg = name
+	} else if len(groups) == 0 {
 		dir := "."
 		if *output != "" {
 			dir = filepath.Dir(*output)
+		} else if *outdir != "" {
+			dir = *outdir
 		}
 
+		// The output directory may not exist
+		// yet, in which case it's created later.
+
 		p, err := build.ImportDir(dir, 0)
+		if _, ok := err.(*build.NoGoError); ok {
+			p.Name, err = guessPackage(dir)
+		} else if _, statErr := os.Stat(dir); os.IsNotExist(statErr) {
+			p.Name, err = guessPackage(dir)
+		}
+
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to determine package name: %v\n", err)
+			errorf("failed to determine package name: %v", err)
 			os.Exit(1)
 		}
 
 		if p.Name == "" || p.Name == "." {
-			fmt.Fprintf(os.Stderr, "Failed to determine package name\n")
+			errorf("failed to determine package name")
 			os.Exit(1)
 		}
 
 		*pkg = p.Name
 	}
 
-	// Output
+	applyFlags(&opts)
+	opts.Cache = loadCache()
+	if len(groups) > 0 {
+		embedGroups(opts)
+		return
+	}
 
-	var (
-		dst *os.File
-		err error
-	)
+	// Inputs
 
-	if *output != "" {
-		dst, err = os.Create(*output)
-		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+	args, err := Args()
+	if err != nil {
+		errorf("failed to read list: %v", err)
+		os.Exit(1)
+	}
+
+	inputs := renames.apply(Inputs(args))
+	renames.unused()
+	total := len(inputs) + failures
+	if *showProgress {
+		progress.start()
+	}
+
+	if *sortInputs {
+		sort.SliceStable(inputs, func(i, j int) bool {
+			return inputs[i].Name < inputs[j].Name
+		})
+	}
+
+	if *stdinName != "" && !readsStdin(inputs) {
+		if err := nameInputs(inputs, *stdinName, *index != ""); err != nil {
+			errorf("%v", err)
+			os.Exit(2)
 		}
+	}
 
-		if err = WritePackage(dst); err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to write package statement: %v\n", err)
-			dst.Close()
+	if *output == "" {
+		embedEach(inputs, opts)
+		if *showProgress {
+			progress.finish()
+		}
+
+		saveCache(opts.Cache)
+		summariseStale()
+		summarise(total)
+		return
+	}
+
+	embedOutput(*output, inputs, opts)
+	if *showProgress {
+		progress.finish()
+	}
+
+	saveCache(opts.Cache)
+	summariseStale()
+	summarise(total)
+}
+
+// embedOutput embeds inputs into the single output
+// with the given name, using opts, exiting at the
+// first fatal failure.
+func embedOutput(name string, inputs []Input, opts embed.Options) {
+	out := embed.NewOutput(opts)
+	if *appendOutput {
+		if err := embed.LoadExisting(out, name); err != nil {
+			errorf("failed to load output: %v", err)
 			os.Exit(1)
 		}
 	}
 
-	// Inputs
+	// The manifest lists the files by the paths
+	// they were read from, so that it can be
+	// checked against them.
+
+	var paths []string
+	for _, in := range inputs {
+		fatal, err := embedInput(out, in)
+		if fatal {
+			errorf("%v", err)
+			os.Exit(1)
+		}
 
-	for _, name := range args {
-		src, err := os.Open(name)
 		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
+			report(in.Origin, err)
 			continue
 		}
 
-		if dst == nil {
-			dst, err = os.Create(filepath.Base(name) + ".go")
-			if err != nil {
-				fmt.Fprintln(os.Stderr, err)
-				src.Close()
-				continue
-			}
+		paths = append(paths, in.Path)
+	}
 
-			if err = WritePackage(dst); err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to write package statement: %v\n", err)
-				dst.Close()
-				src.Close()
-				os.Exit(1)
-			}
+	if *verbose {
+		var s stats
+		for _, file := range out.Files {
+			s.add(file)
 		}
 
-		if err = Embed(dst, src, name); err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to embed data: %v\n", err)
-			dst.Close()
-			src.Close()
-			os.Exit(1)
+		s.print()
+	}
+
+	if err := embed.Finish(out); err != nil {
+		errorf("%v", err)
+		os.Exit(1)
+	}
+
+	// With -use-goembed, the data is written
+	// first, so the output never refers to
+	// files that are missing.
+
+	for _, file := range out.Files {
+		if file.EmbedPath == "" {
+			continue
 		}
 
-		if err = src.Close(); err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to close source: %v\n", err)
-			dst.Close()
+		data := filepath.Join(filepath.Dir(name), filepath.FromSlash(file.EmbedPath))
+		if err := writeData(data, file.Data); err != nil {
+			errorf("failed to write %s: %v", data, err)
 			os.Exit(1)
 		}
+	}
 
-		if *output == "" {
-			if err = dst.Close(); err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to close output: %v\n", err)
-				os.Exit(1)
+	if err := writeFiles(name, out); err != nil {
+		errorf("failed to write output: %v", err)
+		os.Exit(1)
+	}
+
+	if *manifest == "" {
+		return
+	}
+
+	sums := make([][]byte, len(out.Files))
+	for i, file := range out.Files {
+		sums[i] = file.Sum
+	}
+
+	list := strings.ReplaceAll(*manifest, PackagePlaceholder, opts.Package)
+	if err := writeData(list, Manifest(paths, sums)); err != nil {
+		errorf("failed to write manifest: %v", err)
+		os.Exit(1)
+	}
+}
+
+// embedGroups embeds the files in each group given
+// with -group into its own package, written to -o
+// with PackagePlaceholder replaced by its name.
+func embedGroups(opts embed.Options) {
+	// Every group's files are found first, so
+	// that the progress and summary cover them
+	// all.
+
+	var total int
+	inputs := make([][]Input, len(groups))
+	for i, g := range groups {
+		inputs[i] = renames.apply(Inputs(g.args()))
+		total += len(inputs[i])
+		if *sortInputs {
+			sort.SliceStable(inputs[i], func(j, k int) bool {
+				return inputs[i][j].Name < inputs[i][k].Name
+			})
+		}
+	}
+
+	renames.unused()
+	total += failures
+	if *showProgress {
+		progress.start()
+	}
+
+	for i, g := range groups {
+		opts.Package = g.pkg
+		embedOutput(strings.ReplaceAll(*output, PackagePlaceholder, g.pkg), inputs[i], opts)
+	}
+
+	if *showProgress {
+		progress.finish()
+	}
+
+	saveCache(opts.Cache)
+	summariseStale()
+	summarise(total)
+}
+
+// guessPackage guesses the name of the package in
+// dir, which has no Go files yet. As with the go
+// command, that's the last element of its import
+// path, ignoring any major version suffix, if it's
+// in a module, or otherwise the directory's name.
+func guessPackage(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	importPath := filepath.ToSlash(abs)
+	for d := abs; ; d = filepath.Dir(d) {
+		if data, err := os.ReadFile(filepath.Join(d, "go.mod")); err == nil {
+			if mod := modulePath(data); mod != "" {
+				rel, err := filepath.Rel(d, abs)
+				if err != nil {
+					return "", err
+				}
+
+				importPath = path.Join(mod, filepath.ToSlash(rel))
 			}
 
-			dst = nil
+			break
+		}
+
+		if filepath.Dir(d) == d {
+			break
 		}
 	}
+
+	name := path.Base(importPath)
+	if len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" && path.Dir(importPath) != "." {
+		name = path.Base(path.Dir(importPath))
+	}
+
+	return packageName(name)
 }
 
-func WritePackage(dst io.Writer) error {
-	_, err := fmt.Fprintf(dst, "// MACHINE GENERATED - DO NOT EDIT //\n\npackage %s\n", *pkg)
-	return err
+// modulePath returns the module path declared
+// in the go.mod file data, if any.
+func modulePath(data []byte) string {
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "module" {
+			continue
+		}
+
+		if mod, err := strconv.Unquote(fields[1]); err == nil {
+			return mod
+		}
+
+		return fields[1]
+	}
+
+	return ""
 }
 
-func Embed(dst io.Writer, src io.Reader, name string) (err error) {
-	if *compress {
-		var wc io.WriteCloser
-		wc, err = gzip.NewWriterLevel(dst, gzip.BestCompression)
-		if err != nil {
-			return err
+// embedInput embeds in into out. Failing to open
+// the input isn't fatal: the input is skipped and
+// the error returned for reporting.
+func embedInput(out *embed.Output, in Input) (fatal bool, err error) {
+	var src io.ReadCloser = os.Stdin
+	if in.Path != "-" {
+		if err = checkSize(in); err != nil {
+			return false, err
 		}
 
-		defer func() {
-			e := wc.Close()
-			if err == nil && e != nil {
-				err = e
-			}
-		}()
+		src, err = os.Open(in.Path)
+		if err != nil {
+			return false, err
+		}
+	} else if *maxFileSize > 0 {
+		src = &limitReader{src, *maxFileSize}
+	}
 
-		dst = wc
+	if err = embed.Embed(out, src, embed.Input{Name: in.Name, Ident: in.Ident, Var: in.Var}); err != nil {
+		src.Close()
+		return true, fmt.Errorf("%s: failed to embed data: %v", in.filename(), err)
 	}
 
-	var hasher hash.Hash
-	if *sha {
-		hasher = sha1.New()
-		dst = io.MultiWriter(dst, hasher)
+	if err = src.Close(); err != nil {
+		return true, fmt.Errorf("%s: failed to close source: %v", in.filename(), err)
 	}
 
-	const BUF_SIZE = 12
+	return false, nil
+}
 
-	var n int
-	var sanitised = sanitise(name)
-	var buf [BUF_SIZE]byte
+// checkSize returns an error if the input's file
+// is larger than -max-file-size, before it's read.
+func checkSize(in Input) error {
+	if *maxFileSize == 0 {
+		return nil
+	}
 
-	_, err = fmt.Fprintf(dst, "\n// %s\nvar %s = []byte{\n", name, sanitised)
+	info, err := os.Stat(in.Path)
 	if err != nil {
 		return err
 	}
 
-	for {
-		n, err = io.ReadFull(src, buf[:])
-		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
-			return err
+	if info.Size() > *maxFileSize {
+		return fmt.Errorf("%s is %d bytes, larger than -max-file-size %d", in.filename(), info.Size(), *maxFileSize)
+	}
+
+	return nil
+}
+
+// limitReader reads from standard input, failing
+// once more than n bytes have been read, as its
+// size can't be checked beforehand.
+type limitReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (l *limitReader) Read(b []byte) (int, error) {
+	n, err := l.ReadCloser.Read(b)
+	if l.n -= int64(n); l.n < 0 {
+		return n, fmt.Errorf("larger than -max-file-size %d", *maxFileSize)
+	}
+
+	return n, err
+}
+
+// embedEach embeds each input into its own output
+// file, named after it, with the given options,
+// using up to -j workers.
+// Failures are reported in input order, exiting
+// at the first fatal one.
+func embedEach(inputs []Input, opts embed.Options) {
+	type result struct {
+		fatal bool
+		err   error
+		files []embed.File
+	}
+
+	workers := *jobs
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	// A dry run or verification prints as it goes,
+	// so the files are embedded in order.
+
+	if *dryRun || *verify {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	results := make([]result, len(inputs))
+	next := make(chan int)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				in := inputs[i]
+				opts := opts
+				opts.Tags = withFileTags(opts.Tags, in.Name)
+				out := embed.NewOutput(opts)
+				fatal, err := embedInput(out, in)
+				if err == nil {
+					if err = embed.Finish(out); err != nil {
+						fatal = true
+					}
+				}
+
+				if err == nil {
+					if err = writeFiles(outputName(in), out); err != nil {
+						fatal, err = true, fmt.Errorf("failed to write output: %v", err)
+					}
+				}
+
+				results[i] = result{fatal, err, out.Files}
+			}
+		}()
+	}
+
+	for i := range inputs {
+		next <- i
+	}
+
+	close(next)
+	wg.Wait()
+
+	var s stats
+	for i, r := range results {
+		if r.fatal {
+			errorf("%v", r.err)
+			os.Exit(1)
 		}
 
-		if n == 0 {
-			break
+		if r.err != nil {
+			report(inputs[i].Origin, r.err)
 		}
 
-		var w bytes.Buffer
-		for i := 0; i < n; i++ {
-			fmt.Fprintf(&w, "0x%02x, ", buf[i])
+		if *verbose {
+			for _, file := range r.files {
+				s.add(file)
+			}
 		}
+	}
 
-		data := w.String()
-		_, err = fmt.Fprintf(dst, "\t%s\n", data[:len(data)-1])
-		if err != nil {
-			return err
+	if *verbose {
+		s.print()
+	}
+}
+
+// outputName returns the name of the file in is
+// written to without -o. Within -outdir, the name
+// includes the file's path, so that files with
+// the same base name don't collide. With
+// -no-auto-tags, the name is changed so that the
+// go command doesn't constrain it by its suffixes.
+func outputName(in Input) string {
+	if *outdir == "" {
+		return untagged(filepath.Base(in.Name)) + ".go"
+	}
+
+	name := filepath.ToSlash(filepath.Clean(in.Name))
+	for strings.HasPrefix(name, "../") {
+		name = name[3:]
+	}
+
+	name = strings.ReplaceAll(strings.TrimPrefix(name, "/"), "/", "_")
+	return filepath.Join(*outdir, untagged(name)+".go")
+}
+
+// writeFiles writes out to the named file, along
+// with its test file if -gentest is given.
+func writeFiles(name string, out *embed.Output) error {
+	if err := WriteFile(name, out); err != nil {
+		return err
+	}
+
+	if !*genTest {
+		return nil
+	}
+
+	test, err := embed.WriteTest(out)
+	if err != nil {
+		return err
+	}
+
+	return WriteFile(strings.TrimSuffix(name, ".go")+"_test.go", test)
+}
+
+// dryRunReport prints what WriteFile would write
+// to the named file with -n: its size, and the
+// identifier and size of each file embedded.
+func dryRunReport(name string, out *embed.Output, size int) error {
+	if name == "-" {
+		name = "standard output"
+	}
+
+	desc := fmt.Sprintf("%s: %d bytes\n", name, size)
+	for _, file := range out.Files {
+		desc += fmt.Sprintf("\t%s: %s, %d bytes\n", file.Ident, file.Path, file.Size)
+	}
+
+	_, err := io.WriteString(os.Stdout, desc)
+	return err
+}
+
+// verified and stale are the numbers of outputs
+// -verify checked and found to be out of date.
+var verified, stale int
+
+// verifyFile compares src with the contents of
+// the named file for -verify, printing a summary
+// of any difference and counting it as stale.
+func verifyFile(name string, src []byte) error {
+	verified++
+	old, err := os.ReadFile(name)
+	if os.IsNotExist(err) {
+		errorf("%s is missing", name)
+		stale++
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(old, src) {
+		return nil
+	}
+
+	have := strings.SplitAfter(string(old), "\n")
+	want := strings.SplitAfter(string(src), "\n")
+	line := 0
+	for line < len(have) && line < len(want) && have[line] == want[line] {
+		line++
+	}
+
+	errorf("%s is out of date: it differs from line %d, and has %d lines rather than %d", name, line+1, bytes.Count(old, []byte("\n")), bytes.Count(src, []byte("\n")))
+	stale++
+	return nil
+}
+
+// summariseStale exits with a summary if -verify
+// found any outputs out of date.
+func summariseStale() {
+	if stale == 0 {
+		return
+	}
+
+	errorf("%d of %d outputs are out of date", stale, verified)
+	os.Exit(1)
+}
+
+// WriteFile writes out to the named file, or to
+// standard output if the name is "-". The file is
+// written to a temporary file in the same directory
+// and renamed into place once complete, so a failed
+// write never leaves a partial file behind. Unless
+// -force is given, the file isn't touched when its
+// contents are unchanged, and a file that wasn't
+// generated isn't overwritten, in case the name
+// was mistyped. With -n, nothing is
+// written and it's described instead, and with
+// -verify it's compared with the file.
+func WriteFile(name string, out *embed.Output) (err error) {
+	src, err := embed.Render(out)
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		return dryRunReport(name, out, len(src))
+	}
+
+	if *verify {
+		return verifyFile(name, src)
+	}
+
+	if name == "-" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+
+	if old, err := os.ReadFile(name); err == nil && !*force {
+		if bytes.Equal(old, src) {
+			return nil
 		}
 
-		if n != BUF_SIZE {
-			break
+		if !out.Appending() && !generated(old) {
+			return fmt.Errorf("%s was not generated by embed, so it won't be overwritten without -force", name)
 		}
 	}
 
-	_, err = fmt.Fprintf(dst, "}\n")
+	return writeAtomic(name, src)
+}
+
+// writeAtomic writes src to the named file via a
+// temporary file, renamed into place once complete,
+// keeping the permissions of any existing file.
+func writeAtomic(name string, src []byte) (err error) {
+	dir, base := filepath.Split(name)
+	if dir == "" {
+		dir = "."
+	}
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(name); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	f, err := os.CreateTemp(dir, "."+base+".*")
 	if err != nil {
 		return err
 	}
 
-	if hasher != nil {
-		sum := hasher.Sum(nil)
-		_, err = fmt.Fprintf(dst, "\n// SHA1 hash of %s\nvar %s_SHA1 = []byte{\n", name, sanitised)
+	defer func() {
 		if err != nil {
-			return err
+			f.Close()
+			os.Remove(f.Name())
 		}
+	}()
 
-		for len(sum) > 0 {
-			n := len(sum)
-			if n > BUF_SIZE {
-				n = BUF_SIZE
-			}
+	if _, err = f.Write(src); err != nil {
+		return err
+	}
 
-			var w bytes.Buffer
-			for i := 0; i < n; i++ {
-				fmt.Fprintf(&w, "0x%02x, ", sum[i])
-			}
+	if err = f.Chmod(mode); err != nil {
+		return err
+	}
 
-			data := w.String()
-			_, err = fmt.Fprintf(dst, "\t%s\n", data[:len(data)-1])
-			if err != nil {
-				return err
-			}
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), name)
+}
+
+// writeData writes data to the named file for
+// -use-goembed, as WriteFile does for outputs.
+// Unlike outputs, any existing file is simply
+// replaced.
+func writeData(name string, data []byte) error {
+	if *dryRun {
+		return nil
+	}
+
+	if *verify {
+		return verifyFile(name, data)
+	}
+
+	if old, err := os.ReadFile(name); err == nil && !*force && bytes.Equal(old, data) {
+		return nil
+	}
+
+	return writeAtomic(name, data)
+}
+
+// stats totals the sizes printed by -v.
+type stats struct {
+	files        int
+	size, stored int64
+}
+
+// add prints the sizes of file and adds
+// them to the totals.
+func (s *stats) add(file embed.File) {
+	fmt.Fprintf(os.Stderr, "%s: %d -> %d bytes (%s)\n", file.Path, file.Size, file.Stored, ratio(file.Stored, file.Size))
+	s.files++
+	s.size += file.Size
+	s.stored += file.Stored
+}
+
+// print prints the totals.
+func (s *stats) print() {
+	fmt.Fprintf(os.Stderr, "total: %d files, %d -> %d bytes (%s)\n", s.files, s.size, s.stored, ratio(s.stored, s.size))
+}
 
-			sum = sum[n:]
+// ratio formats stored as a percentage of size.
+func ratio(stored, size int64) string {
+	if size == 0 {
+		return "-"
+	}
+
+	return fmt.Sprintf("%.1f%%", float64(stored)*100/float64(size))
+}
+
+// parseHeader reads -header, which is either the
+// name of a file containing the header, or the
+// header itself. Lines which aren't already
+// comments are commented.
+func parseHeader() (string, error) {
+	text := *headerFlag
+	if data, err := os.ReadFile(text); err == nil {
+		text = string(data)
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	lines := strings.Split(strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), "\n"), "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "//"):
+		case line == "":
+			lines[i] = "//"
+		default:
+			lines[i] = "// " + line
 		}
+	}
 
-		_, err = fmt.Fprintf(dst, "}\n")
-		if err != nil {
-			return err
+	return strings.Join(lines, "\n"), nil
+}
+
+// parseTags parses -tags, which may be written
+// in either //go:build or // +build syntax.
+func parseTags() (constraint.Expr, error) {
+	if strings.ContainsAny(*tags, "&|()") {
+		return constraint.Parse("//go:build " + *tags)
+	}
+
+	// The // +build parser ignores invalid tags
+	// rather than failing, so they're checked here.
+
+	for _, term := range strings.FieldsFunc(*tags, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		tag := strings.TrimPrefix(term, "!")
+		if tag == "" || strings.IndexFunc(tag, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.'
+		}) >= 0 {
+			return nil, fmt.Errorf("invalid build tag %q", term)
 		}
 	}
 
-	return err
+	return constraint.Parse("// +build " + *tags)
+}
+
+// generateDirective returns a go:generate directive
+// that repeats this invocation. Note that go generate
+// runs commands in the directory of the file. Any
+// argument that go generate would split or unquote
+// is quoted.
+func generateDirective() string {
+	args := []string{"//go:generate", "embed"}
+	for _, arg := range os.Args[1:] {
+		if arg == "" || strings.ContainsAny(arg, " \t\"\\") {
+			arg = strconv.Quote(arg)
+		}
+
+		args = append(args, arg)
+	}
+
+	return strings.Join(args, " ")
 }
 
-func sanitise(name string) string {
-	var buf bytes.Buffer
-	var first = true
+// legacyMarker is the comment that marked generated
+// files before the Go convention was followed.
+const legacyMarker = "// MACHINE GENERATED - DO NOT EDIT //"
 
-	name = filepath.Base(name)
-	for len(name) > 0 {
-		r, n := utf8.DecodeRuneInString(name)
-		if unicode.IsLetter(r) || (!first && unicode.IsNumber(r)) {
-			first = false
-			buf.WriteRune(r)
-		} else {
-			buf.WriteByte('_')
+// generated reports whether the file data begins
+// with a comment marking it as generated by embed,
+// after any header comments. Besides -marker, this
+// is the default marker and the one embed used to
+// write. Files generated by other tools are not
+// overwritten.
+func generated(data []byte) bool {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == *marker || line == embed.DefaultMarker || line == legacyMarker {
+			return true
 		}
 
-		name = name[n:]
+		if line != "" && !strings.HasPrefix(line, "//") {
+			return false
+		}
 	}
 
-	if buf.Len() == 0 {
-		buf.WriteByte('_')
+	return false
+}
+
+// packageName returns name as a package name,
+// replacing any characters that aren't valid in
+// identifiers. Unlike the generated identifiers,
+// names that would be invalid aren't prefixed, as
+// the result would be a package name no importer
+// expects.
+func packageName(name string) (string, error) {
+	r, _ := utf8.DecodeRuneInString(name)
+	switch {
+	case name == "":
+		return "", errors.New("package name is empty")
+	case token.IsKeyword(name):
+		return "", fmt.Errorf("%q is a keyword", name)
+	case !unicode.IsLetter(r):
+		return "", fmt.Errorf("%q must start with a letter", name)
 	}
 
-	return buf.String()
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			return r
+		}
+
+		return '_'
+	}, name), nil
 }
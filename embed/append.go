@@ -0,0 +1,154 @@
+package embed
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// LoadExisting prepares out to be appended to the
+// existing file with the given name, if there is
+// one. The file must be in the same package, and
+// may not already declare the identifiers that
+// Embed would use.
+func LoadExisting(out *Output, name string) error {
+	src, err := os.ReadFile(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	f, err := parser.ParseFile(token.NewFileSet(), name, src, parser.ImportsOnly)
+	if err != nil {
+		return err
+	}
+
+	if f.Name.Name != out.opts.Package {
+		return fmt.Errorf("%s is in package %s, not %s", name, f.Name.Name, out.opts.Package)
+	}
+
+	out.imported = make(map[string]bool)
+	for _, spec := range f.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return err
+		}
+
+		out.imported[path] = true
+	}
+
+	// ImportsOnly stops before the declarations,
+	// so the file is parsed again for those.
+
+	f, err = parser.ParseFile(token.NewFileSet(), name, src, 0)
+	if err != nil {
+		return err
+	}
+
+	out.declared = make(map[string]bool)
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil {
+				out.declared[decl.Name.Name] = true
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						out.declared[name.Name] = true
+					}
+				case *ast.TypeSpec:
+					out.declared[spec.Name.Name] = true
+				}
+			}
+		}
+	}
+
+	// The identifiers are also reserved, so that
+	// the file's declarations are never reused.
+
+	for ident := range out.declared {
+		out.Ident(ident)
+	}
+
+	// New imports are added to the last import
+	// declaration, or after the package clause if
+	// there isn't one.
+
+	out.insert = int(f.Name.End()) - 1
+	for _, decl := range f.Decls {
+		if decl, ok := decl.(*ast.GenDecl); ok && decl.Tok == token.IMPORT {
+			out.importDecl = decl
+		}
+	}
+
+	out.existing = src
+	return nil
+}
+
+// writeAppended writes the existing file that out
+// is appended to, with any imports it's missing,
+// followed by out's declarations.
+func writeAppended(dst io.Writer, out *Output) error {
+	var missing []string
+	for path := range out.imports {
+		if !out.imported[path] {
+			missing = append(missing, path)
+		}
+	}
+
+	sort.Strings(missing)
+
+	var specs string
+	for _, path := range missing {
+		specs += fmt.Sprintf("\t%q\n", path)
+	}
+
+	src := out.existing
+	decl := out.importDecl
+	switch {
+	case len(missing) == 0:
+	case decl == nil:
+		src = splice(src, out.insert, "\n\nimport (\n"+specs+")")
+	case decl.Lparen.IsValid():
+		// Missing imports join the others in
+		// the block, on a line of their own.
+
+		end := int(decl.Rparen) - 1
+		if end > 0 && src[end-1] != '\n' {
+			specs = "\n" + specs
+		}
+
+		src = splice(src, end, specs)
+	default:
+		// A single import is grouped with the
+		// missing ones.
+
+		start, end := int(decl.Specs[0].Pos())-1, int(decl.Specs[0].End())-1
+		src = splice(src, end, "\n"+specs+")")
+		src = splice(src, start, "(\n\t")
+	}
+
+	if _, err := dst.Write(src); err != nil {
+		return err
+	}
+
+	_, err := out.WriteTo(dst)
+	return err
+}
+
+// splice returns src with s inserted at offset
+// off.
+func splice(src []byte, off int, s string) []byte {
+	return append(append(append([]byte(nil), src[:off]...), s...), src[off:]...)
+}
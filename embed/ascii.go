@@ -0,0 +1,73 @@
+package embed
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// transliterations maps the accented and other
+// Latin letters without an ASCII form to their
+// closest ASCII letters.
+var transliterations = func() map[rune]string {
+	m := make(map[rune]string)
+	for _, t := range []struct{ from, to string }{
+		{"ÀÁÂÃÄÅĀĂĄ", "A"}, {"àáâãäåāăą", "a"},
+		{"ÇĆĈĊČ", "C"}, {"çćĉċč", "c"},
+		{"ÐĎĐ", "D"}, {"ðďđ", "d"},
+		{"ÈÉÊËĒĔĖĘĚ", "E"}, {"èéêëēĕėęě", "e"},
+		{"ĜĞĠĢ", "G"}, {"ĝğġģ", "g"},
+		{"ĤĦ", "H"}, {"ĥħ", "h"},
+		{"ÌÍÎÏĨĪĬĮİ", "I"}, {"ìíîïĩīĭįı", "i"},
+		{"Ĵ", "J"}, {"ĵ", "j"},
+		{"Ķ", "K"}, {"ķĸ", "k"},
+		{"ĹĻĽĿŁ", "L"}, {"ĺļľŀł", "l"},
+		{"ÑŃŅŇŊ", "N"}, {"ñńņňŉŋ", "n"},
+		{"ÒÓÔÕÖØŌŎŐ", "O"}, {"òóôõöøōŏő", "o"},
+		{"ŔŖŘ", "R"}, {"ŕŗř", "r"},
+		{"ŚŜŞŠ", "S"}, {"śŝşšſ", "s"},
+		{"ŢŤŦ", "T"}, {"ţťŧ", "t"},
+		{"ÙÚÛÜŨŪŬŮŰŲ", "U"}, {"ùúûüũūŭůűų", "u"},
+		{"Ŵ", "W"}, {"ŵ", "w"},
+		{"ÝŶŸ", "Y"}, {"ýÿŷ", "y"},
+		{"ŹŻŽ", "Z"}, {"źżž", "z"},
+		{"Æ", "AE"}, {"æ", "ae"},
+		{"Ĳ", "IJ"}, {"ĳ", "ij"},
+		{"Œ", "OE"}, {"œ", "oe"},
+		{"Þ", "TH"}, {"þ", "th"},
+		{"ß", "ss"},
+	} {
+		for _, r := range t.from {
+			m[r] = t.to
+		}
+	}
+
+	return m
+}()
+
+// ascii returns name with its non-ASCII letters
+// and digits transliterated to ASCII, for
+// ASCIINames. Combining marks are dropped, and
+// letters with no ASCII form, such as CJK, are
+// written as their code points, like u65e5, so
+// different names stay distinct. Anything else
+// is left for sanitise to replace.
+func ascii(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r < utf8.RuneSelf:
+			b.WriteRune(r)
+		case unicode.Is(unicode.Mn, r):
+		case transliterations[r] != "":
+			b.WriteString(transliterations[r])
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			fmt.Fprintf(&b, "u%04x", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,75 @@
+package embed
+
+import (
+	"fmt"
+)
+
+// The identifiers used by Blob for the data
+// for every file and the index into it.
+const (
+	blobName  = "_blob"
+	blobIndex = "_blobIndex"
+)
+
+// blobWriter returns the writer appending to
+// o's blob.
+func (o *Output) blobWriter() *byteSliceWriter {
+	if o.blob == nil {
+		o.blob = &byteSliceWriter{w: &o.blobData, width: o.opts.Width, group: o.opts.Pretty}
+	}
+
+	return o.blob
+}
+
+// WriteBlob writes the blob holding the contents
+// of each file embedded in out with Blob, along
+// with the index into it and the Get function
+// that looks files up. Rather than declaring a
+// variable for each file, which can slow down
+// linking when there are many small files, the
+// contents are sliced out of the blob.
+func WriteBlob(out *Output) error {
+	w := out.blobWriter()
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(out, "\n// %s holds the contents of the embedded files.\nvar %[1]s = []byte{", blobName)
+	if err != nil {
+		return err
+	}
+
+	if _, err = out.blobData.WriteTo(out); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(out, "}\n\n// %s holds the offset and length in %s\n// of each embedded file.\nvar %[1]s = map[string][2]int{\n", blobIndex, blobName)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range out.Files {
+		if _, err = fmt.Fprintf(out, "\t%q: {%d, %d},\n", file.Path, file.Offset, file.Size); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(out, blobTemplate, blobName, blobIndex)
+	return err
+}
+
+const blobTemplate = `}
+
+// Get returns the contents of the embedded file
+// with the given path, or nil if there isn't one.
+// The contents share their storage with the other
+// files, so they must not be modified.
+func Get(name string) []byte {
+	i, ok := %[2]s[name]
+	if !ok {
+		return nil
+	}
+
+	return %[1]s[i[0] : i[0]+i[1] : i[0]+i[1]]
+}
+`
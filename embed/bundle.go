@@ -0,0 +1,176 @@
+package embed
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+)
+
+// The identifiers used by Bundle for the
+// compressed archive of every file and the
+// state extracted from it.
+const (
+	bundleName  = "_bundle"
+	bundleState = "_bundleState"
+	bundleFile  = "_bundleFile"
+)
+
+// bundleEntry collects the contents of a file
+// embedded with Bundle, adding it to the
+// archive when closed.
+type bundleEntry struct {
+	o    *Output
+	name string
+	mode os.FileMode
+	data bytes.Buffer
+}
+
+func (e *bundleEntry) Write(b []byte) (int, error) {
+	return e.data.Write(b)
+}
+
+func (e *bundleEntry) Close() error {
+	if e.o.bundle == nil {
+		e.o.bundle = tar.NewWriter(&e.o.bundleData)
+	}
+
+	mode := e.mode
+	if mode == 0 {
+		mode = 0644
+	}
+
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     e.name,
+		Mode:     int64(mode),
+		Size:     int64(e.data.Len()),
+	}
+
+	if err := e.o.bundle.WriteHeader(hdr); err != nil {
+		return err
+	}
+
+	_, err := e.data.WriteTo(e.o.bundle)
+	return err
+}
+
+// WriteBundle writes the archive holding each file
+// embedded in out with Bundle, compressed with
+// gzip as a whole, along with the functions that
+// extract files from it and the AssetNames
+// function listing them. As files often share a
+// lot of their contents, this compresses far
+// better than compressing each file alone.
+func WriteBundle(out *Output) error {
+	if out.bundle == nil {
+		out.bundle = tar.NewWriter(&out.bundleData)
+	}
+
+	if err := out.bundle.Close(); err != nil {
+		return err
+	}
+
+	out.Import("archive/tar")
+	out.Import("bytes")
+	out.Import("compress/gzip")
+	out.Import("fmt")
+	out.Import("io")
+	out.Import("sync")
+
+	_, err := fmt.Fprintf(out, "\n// %s holds the embedded files, as a tar archive\n// compressed with gzip.\nvar %[1]s = []byte{", bundleName)
+	if err != nil {
+		return err
+	}
+
+	w := &byteSliceWriter{w: out, width: out.opts.Width, group: out.opts.Pretty}
+	zw, err := gzip.NewWriterLevel(w, out.opts.Level)
+	if err != nil {
+		return err
+	}
+
+	if _, err = out.bundleData.WriteTo(zw); err != nil {
+		return err
+	}
+
+	if err = zw.Close(); err != nil {
+		return err
+	}
+
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(out, bundleTemplate, bundleName, bundleState, bundleFile)
+	return err
+}
+
+const bundleTemplate = `}
+
+// %[2]s holds the files extracted from %[1]s,
+// which is only extracted once it's needed.
+var %[2]s struct {
+	once  sync.Once
+	files map[string][]byte
+	names []string
+	err   error
+}
+
+// %[3]s returns a copy of the contents of the
+// embedded file with the given path.
+func %[3]s(name string) ([]byte, error) {
+	%[2]s.once.Do(extract%[1]s)
+	if %[2]s.err != nil {
+		return nil, %[2]s.err
+	}
+
+	data, ok := %[2]s.files[name]
+	if !ok {
+		return nil, fmt.Errorf("file not found in bundle: %%q", name)
+	}
+
+	return append([]byte(nil), data...), nil
+}
+
+// AssetNames returns the paths of the embedded
+// files, in the order they were embedded.
+func AssetNames() []string {
+	%[2]s.once.Do(extract%[1]s)
+	return append([]string(nil), %[2]s.names...)
+}
+
+func extract%[1]s() {
+	zr, err := gzip.NewReader(bytes.NewReader(%[1]s))
+	if err != nil {
+		%[2]s.err = err
+		return
+	}
+
+	files := make(map[string][]byte)
+	var names []string
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			%[2]s.err = err
+			return
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			%[2]s.err = err
+			return
+		}
+
+		files[hdr.Name] = data
+		names = append(names, hdr.Name)
+	}
+
+	%[2]s.files, %[2]s.names = files, names
+}
+`
@@ -0,0 +1,275 @@
+package embed
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// cacheVersion is incremented whenever the
+// format of the cache, or the declarations that
+// Embed writes, change.
+const cacheVersion = 2
+
+// Cache holds the declarations Embed wrote for
+// each file, keyed by its contents, so that later
+// runs given the same options can copy them rather
+// than embedding unchanged files again. Files are
+// only copied from the cache if that gives exactly
+// the same declarations, so the output never
+// depends on whether the cache was used. Caches may
+// be shared by Outputs used concurrently, and are
+// created with NewCache or DecodeCache.
+type Cache struct {
+	key string
+
+	mu   sync.Mutex
+	old  map[string]*fragment // Loaded from an earlier run.
+	used map[string]*fragment // Embedded or copied in this run.
+}
+
+// fragment records what Embed did for one file,
+// so that it can be replayed.
+type fragment struct {
+	Idents  []identCall
+	Imports []string
+	Digests []digestEntry
+	Src     []byte
+	File    File
+}
+
+// identCall records an identifier reserved with
+// Output.Ident.
+type identCall struct {
+	Name     string
+	Suffixes []string
+	Ident    string
+}
+
+// digestEntry records data added to an Output's
+// digests.
+type digestEntry struct {
+	Sum        [sha256.Size]byte
+	Compressed bool
+	Original   string
+}
+
+// cacheFile is the encoded form of a Cache. The
+// fragments are sorted by key, so that an
+// unchanged cache is encoded identically.
+type cacheFile struct {
+	Version   int
+	Key       string
+	Keys      []string
+	Fragments []*fragment
+}
+
+// NewCache returns an empty Cache for Outputs
+// whose options are described by key, which must
+// change whenever the options do.
+func NewCache(key string) *Cache {
+	return &Cache{key: key, old: make(map[string]*fragment), used: make(map[string]*fragment)}
+}
+
+// DecodeCache returns the Cache encoded in data by
+// Encode. If it can't be decoded, or was made for
+// another key or version of embed, an empty Cache
+// is returned instead, as if there were none.
+func DecodeCache(data []byte, key string) *Cache {
+	c := NewCache(key)
+	var f cacheFile
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&f); err != nil {
+		return c
+	}
+
+	if f.Version != cacheVersion || f.Key != key || len(f.Keys) != len(f.Fragments) {
+		return c
+	}
+
+	for i, key := range f.Keys {
+		c.old[key] = f.Fragments[i]
+	}
+
+	return c
+}
+
+// Encode returns the encoded form of c, which
+// holds only the files embedded since it was
+// created or decoded, so that files no longer
+// embedded are dropped.
+func (c *Cache) Encode() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f := cacheFile{Version: cacheVersion, Key: c.key}
+	for key := range c.used {
+		f.Keys = append(f.Keys, key)
+	}
+
+	sort.Strings(f.Keys)
+	for _, key := range f.Keys {
+		f.Fragments = append(f.Fragments, c.used[key])
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&f); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// cacheable reports whether files embedded with
+// opts can be cached. Blob, Bundle and GoEmbed
+// can't, as each file's declarations depend on
+// the others, or on data written elsewhere.
+func cacheable(opts *Options) bool {
+	return opts.Cache != nil && !opts.Blob && !opts.Bundle && opts.GoEmbed == ""
+}
+
+// statReader is a reader that still reports the
+// FileInfo of the file it was read from, for the
+// modification time and permissions.
+type statReader struct {
+	*bytes.Reader
+	info os.FileInfo
+}
+
+func (s statReader) Stat() (os.FileInfo, error) {
+	return s.info, nil
+}
+
+// embed embeds src into dst as Embed does, copying
+// the declarations from the cache if they're there
+// and still valid, and caching them otherwise.
+func (c *Cache) embed(dst *Output, src io.Reader, in Input) error {
+	var info os.FileInfo
+	if f, ok := src.(interface{ Stat() (os.FileInfo, error) }); ok {
+		var err error
+		if info, err = f.Stat(); err != nil {
+			return err
+		}
+	}
+
+	content, err := io.ReadAll(src)
+	if err != nil {
+		return err
+	}
+
+	// The key covers everything Embed reads, other
+	// than the options, which are covered by the
+	// cache's key.
+
+	h := sha256.New()
+	h.Write(content)
+	for _, s := range []string{in.Name, in.Ident, in.Var} {
+		h.Write([]byte(strconv.Quote(s)))
+	}
+
+	if info != nil && info.Mode().IsRegular() {
+		h.Write([]byte(info.Mode().Perm().String()))
+		if dst.opts.ModTime {
+			h.Write([]byte(info.ModTime().UTC().Format(time.RFC3339Nano)))
+		}
+	}
+
+	key := hex.EncodeToString(h.Sum(nil))
+	c.mu.Lock()
+	f := c.old[key]
+	if f == nil {
+		f = c.used[key]
+	}
+	c.mu.Unlock()
+
+	if f != nil && dst.replay(f) {
+		c.mu.Lock()
+		c.used[key] = f
+		c.mu.Unlock()
+		return nil
+	}
+
+	src = bytes.NewReader(content)
+	if info != nil {
+		src = statReader{bytes.NewReader(content), info}
+	}
+
+	start := dst.Len()
+	dst.journal = new(fragment)
+	defer func() { dst.journal = nil }()
+	if err = embed(dst, src, in); err != nil {
+		return err
+	}
+
+	// Duplicates refer to the data of an earlier
+	// file, so they're only valid alongside it,
+	// and aren't cached.
+
+	f = dst.journal
+	if len(f.Digests) == 0 {
+		return nil
+	}
+
+	f.Src = append([]byte(nil), dst.Bytes()[start:]...)
+	f.File = dst.Files[len(dst.Files)-1]
+	c.mu.Lock()
+	c.used[key] = f
+	c.mu.Unlock()
+	return nil
+}
+
+// replay copies the declarations in f to o, if
+// they would be the same as embedding the file
+// again, and reports whether it did.
+func (o *Output) replay(f *fragment) bool {
+	for _, d := range f.Digests {
+		if _, ok := o.digests[digest{d.Sum, d.Compressed}]; ok {
+			return false
+		}
+	}
+
+	// Each identifier must be the one Ident would
+	// give now, which depends on those reserved
+	// before it.
+
+	taken := make(map[string]bool)
+	for _, call := range f.Idents {
+		if o.next(call.Name, call.Suffixes, taken) != call.Ident {
+			return false
+		}
+
+		taken[call.Ident] = true
+		for _, suffix := range call.Suffixes {
+			taken[call.Ident+suffix] = true
+		}
+	}
+
+	for _, call := range f.Idents {
+		o.Ident(call.Name, call.Suffixes...)
+	}
+
+	for _, path := range f.Imports {
+		o.Import(path)
+	}
+
+	if o.digests == nil {
+		o.digests = make(map[digest]string)
+	}
+
+	for _, d := range f.Digests {
+		o.digests[digest{d.Sum, d.Compressed}] = d.Original
+	}
+
+	if o.opts.Progress != nil {
+		o.opts.Progress(f.File.Path, int(f.File.Size))
+	}
+
+	o.Write(f.Src)
+	o.Files = append(o.Files, f.File)
+	return true
+}
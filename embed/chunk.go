@@ -0,0 +1,184 @@
+package embed
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// chunkWriter writes data as a []byte variable
+// named ident. If there's more than size bytes,
+// the data is split across variables named
+// ident_0, ident_1, and so on, as reserved in o,
+// with ident initialised by joining them. This
+// keeps each composite literal small enough for
+// the compiler to handle quickly.
+type chunkWriter struct {
+	o      *Output
+	ident  string
+	size   int
+	width  int          // Bytes per line.
+	group  int          // Lines per block, as for Pretty.
+	buf    bytes.Buffer // Current chunk, formatted.
+	data   *byteSliceWriter
+	n      int      // Bytes in the current chunk.
+	chunks []string // Names of the chunks written.
+	total  int
+}
+
+func (c *chunkWriter) Write(p []byte) (n int, err error) {
+	if c.data == nil {
+		c.data = &byteSliceWriter{w: &c.buf, width: c.width, group: c.group}
+	}
+
+	// Chunks are only written once more data
+	// arrives, so that data of exactly size
+	// bytes isn't split.
+
+	for len(p) > 0 {
+		if c.n == c.size {
+			if err = c.flush(); err != nil {
+				return n, err
+			}
+		}
+
+		m := c.size - c.n
+		if m > len(p) {
+			m = len(p)
+		}
+
+		c.data.Write(p[:m])
+		c.n += m
+		c.total += m
+		n += m
+		p = p[m:]
+	}
+
+	return n, nil
+}
+
+// flush writes the current chunk.
+func (c *chunkWriter) flush() error {
+	c.data.Close()
+	name := c.o.Ident(fmt.Sprintf("%s_%d", c.ident, len(c.chunks)))
+	_, err := fmt.Fprintf(c.o, "var %s = []byte{%s}\n\n", name, c.buf.Bytes())
+	c.buf.Reset()
+	c.data = &byteSliceWriter{w: &c.buf, width: c.width, group: c.group, offset: int64(c.total)}
+	c.n = 0
+	c.chunks = append(c.chunks, name)
+	return err
+}
+
+func (c *chunkWriter) Close() error {
+	if c.data == nil {
+		c.data = &byteSliceWriter{w: &c.buf, width: c.width, group: c.group}
+	}
+
+	if len(c.chunks) == 0 {
+		c.data.Close()
+		_, err := fmt.Fprintf(c.o, "var %s = []byte{%s}\n", c.ident, c.buf.Bytes())
+		return err
+	}
+
+	if err := c.flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(c.o, "var %s = func() []byte {\n\tb := make([]byte, 0, %d)\n\tfor _, chunk := range [][]byte{\n", c.ident, c.total)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range c.chunks {
+		if _, err = fmt.Fprintf(c.o, "\t\t%s,\n", name); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(c.o, "\t} {\n\t\tb = append(b, chunk...)\n\t}\n\n\treturn b\n}()\n")
+	return err
+}
+
+// stringChunkWriter writes the contents of a string
+// literal, as written by stringWriter or an
+// Encoding, as a string named ident, declared with
+// decl. If there's more than size bytes, the
+// literal is split across constants named ident_0,
+// ident_1, and so on, as reserved in o, with ident
+// declared as their concatenation, which the
+// compiler handles far faster than one huge
+// literal. Escape sequences are never split.
+type stringChunkWriter struct {
+	o         *Output
+	ident     string
+	decl      string
+	size      int
+	buf       bytes.Buffer // Current chunk.
+	chunks    []string     // Names of the chunks written.
+	backslash bool         // Whether the last byte started an escape sequence.
+	escape    int          // Bytes left in the current escape sequence.
+}
+
+func (s *stringChunkWriter) Write(p []byte) (int, error) {
+	for _, c := range p {
+		if !s.backslash && s.escape == 0 && s.buf.Len() >= s.size {
+			if err := s.flush(); err != nil {
+				return 0, err
+			}
+		}
+
+		s.buf.WriteByte(c)
+		switch {
+		case s.backslash:
+			s.backslash = false
+			if c == 'x' {
+				s.escape = 2
+			}
+		case s.escape > 0:
+			s.escape--
+		case c == '\\':
+			s.backslash = true
+		}
+	}
+
+	return len(p), nil
+}
+
+// flush writes the current chunk.
+func (s *stringChunkWriter) flush() error {
+	name := s.o.Ident(fmt.Sprintf("%s_%d", s.ident, len(s.chunks)))
+	_, err := fmt.Fprintf(s.o, "const %s = \"%s\"\n", name, s.buf.Bytes())
+	s.buf.Reset()
+	s.chunks = append(s.chunks, name)
+	return err
+}
+
+func (s *stringChunkWriter) Close() error {
+	if len(s.chunks) == 0 {
+		_, err := fmt.Fprintf(s.o, "%s %s = \"%s\"\n", s.decl, s.ident, s.buf.Bytes())
+		return err
+	}
+
+	if err := s.flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(s.o, "\n%s %s = %s\n", s.decl, s.ident, strings.Join(s.chunks, " +\n\t"))
+	return err
+}
+
+// closeChain closes its writer, and then next,
+// for writers that write through another.
+type closeChain struct {
+	io.WriteCloser
+	next io.Closer
+}
+
+func (c closeChain) Close() error {
+	if err := c.WriteCloser.Close(); err != nil {
+		return err
+	}
+
+	return c.next.Close()
+}
@@ -0,0 +1,104 @@
+package embed
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Compressor is a compression format that data
+// can be embedded in.
+type Compressor struct {
+	NewWriter func(w io.Writer, level int) (io.WriteCloser, error)
+	Reader    string // Format of the expressions yielding a decompressing reader and an error.
+	Suffix    string // Suffix of the identifier holding the compressed data.
+	Package   string // Import path of the implementation.
+}
+
+// Compressors contains the formats data can be
+// compressed in, by name.
+var Compressors = map[string]*Compressor{
+	"gzip": {
+		func(w io.Writer, level int) (io.WriteCloser, error) { return gzip.NewWriterLevel(w, level) },
+		"gzip.NewReader(%s)", "_gz", "compress/gzip",
+	},
+	"zlib": {
+		func(w io.Writer, level int) (io.WriteCloser, error) { return zlib.NewWriterLevel(w, level) },
+		"zlib.NewReader(%s)", "_zlib", "compress/zlib",
+	},
+	"flate": {
+		func(w io.Writer, level int) (io.WriteCloser, error) { return flate.NewWriter(w, level) },
+		"flate.NewReader(%s), nil", "_flate", "compress/flate",
+	},
+}
+
+// compressedExts contains the extensions of
+// formats that are already compressed.
+var compressedExts = map[string]bool{
+	".7z":    true,
+	".br":    true,
+	".bz2":   true,
+	".gif":   true,
+	".gz":    true,
+	".jpeg":  true,
+	".jpg":   true,
+	".mp3":   true,
+	".mp4":   true,
+	".ogg":   true,
+	".png":   true,
+	".tgz":   true,
+	".webm":  true,
+	".webp":  true,
+	".woff":  true,
+	".woff2": true,
+	".xz":    true,
+	".zip":   true,
+	".zst":   true,
+}
+
+// worthCompressing reports whether compressing
+// the named file's contents as given by opts
+// makes them smaller. Files in compressed formats
+// are assumed not to, and anything else is
+// compressed to see.
+func worthCompressing(opts *Options, name string, content []byte) (bool, error) {
+	if compressedExts[strings.ToLower(filepath.Ext(name))] {
+		return false, nil
+	}
+
+	var n countWriter
+	w, err := opts.Compression.NewWriter(&n, opts.Level)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err = w.Write(content); err != nil {
+		return false, err
+	}
+
+	if err = w.Close(); err != nil {
+		return false, err
+	}
+
+	return int(n) < len(content), nil
+}
+
+// countWriter counts the bytes written to it.
+type countWriter int64
+
+func (n *countWriter) Write(b []byte) (int, error) {
+	*n += countWriter(len(b))
+	return len(b), nil
+}
+
+// decompress returns the expressions yielding a
+// reader decompressing the data read by r and an
+// error, importing the implementation.
+func decompress(dst *Output, r string) string {
+	dst.Import(dst.opts.Compression.Package)
+	return fmt.Sprintf(dst.opts.Compression.Reader, r)
+}
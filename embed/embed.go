@@ -0,0 +1,1453 @@
+// Package embed generates Go source embedding the
+// contents of files, as the embed command does.
+//
+// Each file is embedded into an Output with Embed,
+// according to the Options the Output was created
+// with. Finish then writes the declarations that
+// cover every file, such as a map or fs.FS, and
+// Render returns the complete source:
+//
+//	out := embed.NewOutput(embed.Options{Package: "assets", Map: "Files"})
+//	if err := embed.Embed(out, f, embed.Input{Name: "index.html", Ident: "index.html"}); err != nil {
+//		return err
+//	}
+//
+//	if err := embed.Finish(out); err != nil {
+//		return err
+//	}
+//
+//	src, err := embed.Render(out)
+package embed
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"go/ast"
+	"go/build/constraint"
+	"go/format"
+	"go/token"
+	"hash"
+	"hash/crc32"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Render returns the complete source of out.
+// Unless disabled with Unformatted, it's
+// formatted.
+func Render(out *Output) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := WritePackage(&buf, out); err != nil {
+		return nil, err
+	}
+
+	if out.opts.Unformatted {
+		return buf.Bytes(), nil
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("gofmt: %v", err)
+	}
+
+	return src, nil
+}
+
+// Output holds the declarations generated for a
+// single Go source file. They are buffered so
+// that the imports they need can be written
+// ahead of them by WritePackage. This also means
+// Embed's many small writes never reach the file
+// system, as the source is rendered in one go.
+// Outputs are created with NewOutput.
+type Output struct {
+	bytes.Buffer
+	Files   []File // Files embedded so far.
+	opts    Options
+	imports map[string]bool
+	idents  map[string]bool
+	test    bool // Whether this is a test file.
+
+	// When appending, the existing file and the
+	// identifiers and imports it declares.
+
+	existing   []byte
+	importDecl *ast.GenDecl // Last import declaration, to add imports to.
+	insert     int          // Offset at which to add imports otherwise.
+	declared   map[string]bool
+	imported   map[string]bool
+
+	// The data already embedded, by the hash
+	// of its contents and the form it's in.
+
+	digests map[digest]string
+
+	// With Blob, the contents of every file.
+
+	blob     *byteSliceWriter
+	blobData bytes.Buffer
+	blobSize int64
+
+	// With Bundle, the archive of every file.
+
+	bundle     *tar.Writer
+	bundleData bytes.Buffer
+
+	// With a Cache, what Embed has done so far
+	// for the file being embedded.
+
+	journal *fragment
+}
+
+// digest identifies data embedded in an Output.
+type digest struct {
+	sum        [sha256.Size]byte
+	compressed bool
+}
+
+// File describes a file embedded in an Output.
+type File struct {
+	Path     string      // Path of the original file.
+	Ident    string      // Identifier the file's declarations are named after.
+	Value    string      // Expression yielding its contents.
+	Fallible bool        // Whether Value also yields an error.
+	Size     int64       // Size of the original contents.
+	Stored   int64       // Size of the data embedded, after any compression.
+	Offset   int64       // Offset of the contents in the blob, with Blob.
+	Mode     os.FileMode // Permission bits of the original file, if known.
+	ModTime  time.Time   // Modification time of the original file, if known and embedded.
+	Sum      []byte      // Hash of the contents, by the first algorithm given.
+
+	// With GoEmbed, the data the caller must write
+	// to EmbedPath, relative to the output, to be
+	// embedded by the go:embed directive.
+
+	Data      []byte
+	EmbedPath string
+}
+
+// Appending reports whether o is appended to an
+// existing file, loaded with LoadExisting.
+func (o *Output) Appending() bool {
+	return o.existing != nil
+}
+
+// replace replaces the n bytes written to o
+// at offset off with s.
+func (o *Output) replace(off, n int, s string) {
+	tail := append([]byte(nil), o.Bytes()[off+n:]...)
+	o.Truncate(off)
+	o.WriteString(s)
+	o.Write(tail)
+}
+
+// Import records that the declarations in o
+// use the package with the given path.
+func (o *Output) Import(path string) {
+	if o.imports == nil {
+		o.imports = make(map[string]bool)
+	}
+
+	o.imports[path] = true
+	if o.journal != nil {
+		o.journal.Imports = append(o.journal.Imports, path)
+	}
+}
+
+// Ident returns a unique identifier in o based on
+// name, which is used as-is unless it, or name
+// with any of the given suffixes, has already
+// been returned, in which case a numeric suffix is
+// added. The identifiers with each suffix are
+// reserved along with it.
+func (o *Output) Ident(name string, suffixes ...string) string {
+	if o.idents == nil {
+		o.idents = make(map[string]bool)
+	}
+
+	ident := o.next(name, suffixes, nil)
+	o.idents[ident] = true
+	for _, suffix := range suffixes {
+		o.idents[ident+suffix] = true
+	}
+
+	if o.journal != nil {
+		o.journal.Idents = append(o.journal.Idents, identCall{name, suffixes, ident})
+	}
+
+	return ident
+}
+
+// next returns the identifier Ident would return,
+// without reserving it. Identifiers in taken are
+// treated as reserved too.
+func (o *Output) next(name string, suffixes []string, taken map[string]bool) string {
+	reserved := func(ident string) bool {
+		if o.idents[ident] || taken[ident] {
+			return true
+		}
+
+		for _, suffix := range suffixes {
+			if o.idents[ident+suffix] || taken[ident+suffix] {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	ident := name
+	for i := 2; reserved(ident); i++ {
+		ident = fmt.Sprintf("%s_%d", name, i)
+	}
+
+	return ident
+}
+
+// WritePackage writes the package clause and
+// any imports needed by out to dst, in a single
+// sorted block as gofmt leaves it, followed by
+// the declarations themselves.
+func WritePackage(dst io.Writer, out *Output) error {
+	if out.existing != nil {
+		return writeAppended(dst, out)
+	}
+
+	opts := &out.opts
+	if opts.Header != "" {
+		if _, err := fmt.Fprintf(dst, "%s\n\n", opts.Header); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(dst, "%s\n\n", opts.Marker)
+	if err != nil {
+		return err
+	}
+
+	if opts.Tags != nil {
+		lines, err := constraint.PlusBuildLines(opts.Tags)
+		if err != nil {
+			return err
+		}
+
+		lines = append([]string{"//go:build " + opts.Tags.String()}, lines...)
+		if _, err = fmt.Fprintf(dst, "%s\n\n", strings.Join(lines, "\n")); err != nil {
+			return err
+		}
+	}
+
+	if _, err = fmt.Fprintf(dst, "package %s\n", opts.Package); err != nil {
+		return err
+	}
+
+	if opts.Generate != "" && !out.test {
+		if _, err = fmt.Fprintf(dst, "\n%s\n", opts.Generate); err != nil {
+			return err
+		}
+	}
+
+	if len(out.imports) > 0 {
+		imports := make([]string, 0, len(out.imports))
+		for path := range out.imports {
+			imports = append(imports, path)
+		}
+
+		sort.Strings(imports)
+
+		if _, err = fmt.Fprintf(dst, "\nimport (\n"); err != nil {
+			return err
+		}
+
+		for _, path := range imports {
+			if _, err = fmt.Fprintf(dst, "\t%q\n", path); err != nil {
+				return err
+			}
+		}
+
+		if _, err = fmt.Fprintf(dst, ")\n"); err != nil {
+			return err
+		}
+	}
+
+	_, err = out.WriteTo(dst)
+	return err
+}
+
+// Embed embeds the data read from src into dst,
+// named after in, in the form given by dst's
+// options.
+func Embed(dst *Output, src io.Reader, in Input) error {
+	if cacheable(&dst.opts) {
+		return dst.opts.Cache.embed(dst, src, in)
+	}
+
+	return embed(dst, src, in)
+}
+
+// embed embeds src into dst, as Embed does,
+// without a Cache.
+func embed(dst *Output, src io.Reader, in Input) (err error) {
+	var opts = &dst.opts
+	var name = in.Name
+	var sanitised = opts.Prefix + trimPrefix(in.Ident, opts.TrimPrefix)
+	if opts.ASCIINames {
+		sanitised = ascii(sanitised)
+	}
+
+	sanitised = sanitise(sanitised)
+	if opts.Export {
+		sanitised = exported(sanitised)
+	}
+
+	if in.Var != "" {
+		sanitised = in.Var
+	}
+
+	if dst.declared[sanitised] {
+		return fmt.Errorf("%s is already declared in the output", sanitised)
+	}
+
+	sanitised = dst.Ident(sanitised, derived(opts)...)
+
+	// Only regular files have a meaningful
+	// modification time and permissions.
+
+	var modTime time.Time
+	var mode os.FileMode
+	if f, ok := src.(interface{ Stat() (os.FileInfo, error) }); ok {
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			modTime = info.ModTime()
+			mode = info.Mode().Perm()
+		}
+	}
+
+	// Text is transcoded first, so that everything
+	// else, including the size and hashes, sees the
+	// converted data. Like line endings, it's only
+	// converted in text files.
+
+	if opts.TranscodeFrom != nil && opts.TranscodeTo != nil && isText(name, opts.TextExts) {
+		content, err := io.ReadAll(src)
+		if err != nil {
+			return err
+		}
+
+		if content, err = transcode(content, opts.TranscodeFrom, opts.TranscodeTo); err != nil {
+			return err
+		}
+
+		src = bytes.NewReader(content)
+	}
+
+	// The content type is sniffed from a buffered
+	// window, so the bytes are still embedded.
+
+	var contentType string
+	if opts.MIME {
+		contentType = mime.TypeByExtension(filepath.Ext(name))
+		if contentType == "" {
+			br := bufio.NewReader(src)
+			sniff, err := br.Peek(512)
+			if err != nil && err != io.EOF {
+				return err
+			}
+
+			contentType = http.DetectContentType(sniff)
+			src = br
+		}
+	}
+
+	// Line endings are only normalised in text,
+	// so binary files are never corrupted.
+
+	if opts.NormalizeEOL && isText(name, opts.TextExts) {
+		src = eolReader{bufio.NewReader(src)}
+	}
+
+	// Only text that can be written verbatim is
+	// embedded as a raw string. Anything else
+	// falls back to the usual form.
+
+	var rawString bool
+	if opts.Raw {
+		content, err := io.ReadAll(src)
+		if err != nil {
+			return err
+		}
+
+		rawString = isRaw(content)
+		src = bytes.NewReader(content)
+	}
+
+	// With SmartCompress, data that compression
+	// wouldn't shrink is stored as it is, behind
+	// the same accessor. Likewise for data under
+	// MinCompressSize, where the compression
+	// overhead outweighs any saving.
+
+	var compressed = opts.Compression != nil
+	if compressed && (opts.SmartCompress || opts.MinCompressSize > 0) {
+		content, err := io.ReadAll(src)
+		if err != nil {
+			return err
+		}
+
+		if int64(len(content)) < opts.MinCompressSize {
+			compressed = false
+		} else if opts.SmartCompress {
+			compressed, err = worthCompressing(opts, name, content)
+			if err != nil {
+				return err
+			}
+		}
+
+		src = bytes.NewReader(content)
+	}
+
+	// Compressed data is hidden behind an
+	// accessor that decompresses it.
+
+	var ident = sanitised
+	if opts.Compression != nil && opts.Encoding == nil {
+		if compressed {
+			ident += opts.Compression.Suffix
+		} else {
+			ident += "_raw"
+		}
+	}
+
+	// With CopyAccessor, the data is only reached
+	// through the accessor, so it's unexported.
+
+	if opts.CopyAccessor {
+		ident = dst.Ident("_" + ident)
+	}
+
+	var hashers []hash.Hash
+	var sums []io.Writer
+	for _, alg := range opts.Hashes {
+		hasher := Hashes[alg].New()
+		hashers = append(hashers, hasher)
+		sums = append(sums, hasher)
+	}
+
+	checksum := crc32.NewIEEE()
+	if opts.CRC32 {
+		sums = append(sums, checksum)
+	}
+
+	// With HashFirst, the hashes are computed
+	// in a first pass over the data, so they can
+	// be written before it.
+
+	if opts.HashFirst && len(sums) > 0 {
+		src, err = firstPass(src, io.MultiWriter(sums...))
+		if err != nil {
+			return err
+		}
+
+		if err = writeHashes(dst, name, sanitised, hashers, checksum); err != nil {
+			return err
+		}
+	}
+
+	// Where the data starts, in case it turns
+	// out to be a duplicate.
+
+	var start = dst.Len()
+	var decl = "var"
+	if opts.Encoding != nil || (opts.Const && (opts.String || rawString)) {
+		decl = "const"
+	}
+	var literal, closing = ident, "\"\n"
+	var preamble = "\n// " + name + "\n"
+	if opts.NoComments {
+		preamble = ""
+	}
+
+	// With LineDirectives, the declarations are
+	// attributed to the original file. Like any
+	// directive, it follows the comment, after an
+	// empty line of its own.
+
+	var directives string
+	if opts.LineDirectives {
+		directives = "//line " + name + ":1\n"
+		if preamble == "" {
+			preamble = "\n" + directives
+		} else {
+			directives = "//\n" + directives
+			preamble += directives
+		}
+	}
+
+	var data io.WriteCloser
+	var embedded bytes.Buffer
+	var embedPath string
+	switch {
+	case opts.GoEmbed != "":
+		// The data is copied next to the output
+		// by the caller, and only the variable
+		// is declared here.
+
+		closing = ""
+		embedPath = path.Join(opts.GoEmbed, fsPath(File{Path: name}))
+		data = nopCloser{&embedded}
+		directive := preamble
+		switch {
+		case preamble == "":
+			directive = "\n"
+		case directives == "":
+			directive += "//\n"
+		}
+
+		_, err = fmt.Fprintf(dst, "%s//go:embed %s\nvar %s []byte\n", directive, embedPattern(embedPath), ident)
+	case opts.Blob:
+		closing = ""
+		data = nopCloser{dst.blobWriter()}
+	case opts.Bundle:
+		closing = ""
+		data = &bundleEntry{o: dst, name: name, mode: mode}
+	case rawString:
+		closing = "`\n"
+		data = nopCloser{dst}
+		_, err = fmt.Fprintf(dst, "%s%s %s = `", preamble, decl, ident)
+	case opts.Encoding != nil && opts.ChunkString > 0:
+		closing = ""
+		literal += opts.Encoding.Suffix
+		sc := &stringChunkWriter{o: dst, ident: literal, decl: "const", size: opts.ChunkString}
+		data = closeChain{opts.Encoding.NewEncoder(sc), sc}
+		_, err = io.WriteString(dst, preamble)
+	case opts.Encoding != nil:
+		literal += opts.Encoding.Suffix
+		data = opts.Encoding.NewEncoder(dst)
+		_, err = fmt.Fprintf(dst, "%sconst %s = \"", preamble, literal)
+	case opts.String && opts.ChunkString > 0:
+		closing = ""
+		sc := &stringChunkWriter{o: dst, ident: ident, decl: decl, size: opts.ChunkString}
+		data = closeChain{&stringWriter{w: sc}, sc}
+		_, err = io.WriteString(dst, preamble)
+	case opts.String:
+		data = &stringWriter{w: dst}
+		_, err = fmt.Fprintf(dst, "%s%s %s = \"", preamble, decl, ident)
+	case opts.Lines:
+		closing = "}\n"
+		data = &linesWriter{w: dst}
+		_, err = fmt.Fprintf(dst, "%svar %s = []string{", preamble, ident)
+	case opts.Chunk > 0:
+		closing = ""
+		data = &chunkWriter{o: dst, ident: ident, size: opts.Chunk, width: opts.Width, group: opts.Pretty}
+		_, err = io.WriteString(dst, preamble)
+	case opts.Array:
+		closing = "}\n"
+		data = &byteSliceWriter{w: dst, width: opts.Width, group: opts.Pretty}
+		_, err = fmt.Fprintf(dst, "%svar %s = [...]byte{", preamble, ident)
+	case opts.Funcs:
+		closing = "}\n}\n"
+		data = &byteSliceWriter{w: dst, width: opts.Width, group: opts.Pretty}
+		_, err = fmt.Fprintf(dst, "%sfunc %s() []byte {\n\treturn []byte{", preamble, ident)
+	default:
+		closing = "}\n"
+		data = &byteSliceWriter{w: dst, width: opts.Width, group: opts.Pretty}
+		_, err = fmt.Fprintf(dst, "%svar %s = []byte{", preamble, ident)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// The hash covers the original contents, so it's
+	// computed before any compression.
+
+	var stored countWriter
+	var w io.Writer = io.MultiWriter(data, &stored)
+	var cw io.WriteCloser
+	if compressed {
+		cw, err = opts.Compression.NewWriter(w, opts.Level)
+		if err != nil {
+			return err
+		}
+
+		w = cw
+	}
+
+	if !opts.HashFirst {
+		w = io.MultiWriter(append([]io.Writer{w}, sums...)...)
+	}
+
+	sum := sha256.New()
+	w = io.MultiWriter(w, sum)
+
+	if opts.Progress != nil {
+		src = progressReader{src, name, opts.Progress}
+	}
+
+	size, err := io.Copy(w, src)
+	if err != nil {
+		return err
+	}
+
+	if cw != nil {
+		if err = cw.Close(); err != nil {
+			return err
+		}
+	}
+
+	if err = data.Close(); err != nil {
+		return err
+	}
+
+	// Gzipped data can be served as it is, with a
+	// Content-Encoding of gzip, so its size is
+	// kept, even for duplicates. Encoded data
+	// can't, as it must be decoded first.
+
+	var gzipped = compressed && opts.Compression == Compressors["gzip"] && opts.Encoding == nil
+	var gzipSize = int64(stored)
+
+	// Data identical to an earlier file's is
+	// replaced with a reference to it, provided
+	// it's stored in the same form. Whether it's
+	// compressed can depend on the file's name.
+
+	var key = digest{compressed: compressed}
+	copy(key.sum[:], sum.Sum(nil))
+	original, duplicate := dst.digests[key]
+	if duplicate && !opts.Blob && !opts.Bundle && opts.GoEmbed == "" {
+		stored = 0
+		dst.Truncate(start)
+		if opts.Funcs {
+			_, err = fmt.Fprintf(dst, "%sfunc %s() []byte {\n\treturn %s()\n}\n", preamble, literal, original)
+		} else {
+			_, err = fmt.Fprintf(dst, "%s%s %s = %s\n", preamble, decl, literal, original)
+		}
+	} else {
+		if dst.digests == nil {
+			dst.digests = make(map[digest]string)
+		}
+
+		dst.digests[key] = literal
+		if dst.journal != nil {
+			dst.journal.Digests = append(dst.journal.Digests, digestEntry{key.sum, key.compressed, literal})
+		}
+		_, err = io.WriteString(dst, closing)
+
+		// Arrays are given their length, now
+		// that it's known.
+
+		if opts.Array {
+			dst.replace(start+len(preamble+"var "+ident+" = ["), len("..."), strconv.FormatInt(int64(stored), 10))
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Arrays are sliced wherever a []byte is
+	// needed, and functions called.
+
+	var slice = ident
+	switch {
+	case opts.Array:
+		slice += "[:]"
+	case opts.Funcs:
+		slice += "()"
+	}
+
+	// With Docs, the comment naming the file is
+	// replaced with a doc comment, now that the
+	// size is known. Chunks are left alone, as
+	// the variable is declared after them.
+
+	if opts.Docs && !opts.Blob && !opts.Bundle && opts.Chunk == 0 && opts.ChunkString == 0 {
+		verb := "holds"
+		if opts.Funcs {
+			verb = "returns"
+		}
+
+		doc := fmt.Sprintf("%s %s the embedded contents of %s (%d bytes)", literal, verb, name, size)
+		switch {
+		case duplicate:
+			doc += ", the same as " + original
+		case compressed:
+			doc += fmt.Sprintf(", compressed with %s to %d bytes", path.Base(opts.Compression.Package), stored)
+		case opts.Compression != nil:
+			doc += ", stored uncompressed"
+		}
+
+		if opts.Encoding != nil {
+			doc += ", encoded in " + opts.Encoding.Name
+		}
+
+		dst.replace(start, len(preamble), "\n// "+doc+".\n"+directives)
+	}
+
+	file := File{Path: name, Ident: sanitised, Value: slice, Size: size, Stored: int64(stored), Mode: mode}
+	if opts.ModTime {
+		file.ModTime = modTime
+	}
+	if len(hashers) > 0 {
+		file.Sum = hashers[0].Sum(nil)
+	}
+	if opts.Blob {
+		file.Offset = dst.blobSize
+		file.Value = fmt.Sprintf("%s[%d:%d:%[3]d]", blobName, file.Offset, file.Offset+size)
+		dst.blobSize += size
+	}
+
+	if opts.GoEmbed != "" {
+		file.Data = embedded.Bytes()
+		file.EmbedPath = embedPath
+	}
+
+	switch {
+	case opts.Bundle:
+		file.Value = sanitised + "()"
+		file.Fallible = true
+		_, err = fmt.Fprintf(dst, "\n// %s returns the contents of %s.\nfunc %[1]s() ([]byte, error) {\n\treturn %[3]s(%[2]q)\n}\n", sanitised, name, bundleFile)
+	case opts.Lines:
+		sep := `"\n"`
+		if !data.(*linesWriter).newline {
+			sep = `""`
+		}
+
+		dst.Import("strings")
+		file.Value = sanitised + "_Bytes()"
+		_, err = fmt.Fprintf(dst, "\nfunc %s_Bytes() []byte {\n\treturn []byte(strings.Join(%s, \"\\n\") + %s)\n}\n", sanitised, ident, sep)
+	case rawString:
+		file.Value = "[]byte(" + ident + ")"
+		_, err = fmt.Fprintf(dst, "\nfunc %s_Bytes() []byte {\n\treturn []byte(%s)\n}\n", sanitised, ident)
+	case opts.Encoding != nil:
+		file.Value = sanitised + "()"
+		err = writeDecodeAccessor(dst, sanitised, literal, compressed)
+	case opts.String:
+		file.Value = "[]byte(" + ident + ")"
+		if compressed {
+			dst.Import("strings")
+			err = writeDecompressAccessor(dst, sanitised, "strings.NewReader("+ident+")")
+		} else if opts.Compression != nil {
+			err = writeStoredAccessor(dst, sanitised, "[]byte("+ident+")")
+		} else {
+			_, err = fmt.Fprintf(dst, "\nfunc %s_Bytes() []byte {\n\treturn []byte(%s)\n}\n", sanitised, ident)
+		}
+	default:
+		if compressed {
+			dst.Import("bytes")
+			err = writeDecompressAccessor(dst, sanitised, "bytes.NewReader("+slice+")")
+		} else if opts.Compression != nil {
+			err = writeStoredAccessor(dst, sanitised, slice)
+		} else if opts.CopyAccessor {
+			file.Value = sanitised + "()"
+			_, err = fmt.Fprintf(dst, "\nfunc %s() []byte {\n\treturn %s\n}\n", sanitised, copied(dst, slice))
+		}
+	}
+
+	if opts.Compression != nil && opts.Encoding == nil {
+		file.Value = sanitised + "()"
+		file.Fallible = true
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if opts.Reader {
+		var r string
+		switch {
+		case opts.Encoding != nil:
+			opts.Encoding.use(dst)
+			dst.Import("strings")
+			r = fmt.Sprintf(opts.Encoding.Decoder, literal)
+		case opts.String, rawString:
+			dst.Import("strings")
+			r = "strings.NewReader(" + ident + ")"
+		default:
+			dst.Import("bytes")
+			r = "bytes.NewReader(" + slice + ")"
+		}
+
+		if err = writeReaderAccessor(dst, sanitised, r, compressed); err != nil {
+			return err
+		}
+	}
+
+	if opts.BytesReader {
+		if err = writeBytesReaderAccessor(dst, file); err != nil {
+			return err
+		}
+	}
+
+	if !opts.HashFirst {
+		if err = writeHashes(dst, name, sanitised, hashers, checksum); err != nil {
+			return err
+		}
+	}
+
+	if (opts.Size && !opts.Info) || gzipped {
+		_, err = fmt.Fprintf(dst, "\n// Size of %s in bytes\nconst %s_Size = %d\n", name, sanitised, size)
+		if err != nil {
+			return err
+		}
+	}
+
+	if gzipped {
+		_, err = fmt.Fprintf(dst, "\n// Size of %s in bytes, compressed with gzip\nconst %s_GzipSize = %d\n", name, sanitised, gzipSize)
+		if err != nil {
+			return err
+		}
+	}
+
+	if (opts.SmartCompress || opts.MinCompressSize > 0) && opts.Compression != nil {
+		_, err = fmt.Fprintf(dst, "\n// Whether %s is stored compressed\nconst %s_Compressed = %t\n", name, sanitised, compressed)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.MIME && !opts.Info {
+		_, err = fmt.Fprintf(dst, "\n// Content type of %s\nconst %s_ContentType = %q\n", name, sanitised, contentType)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.ModTime && !opts.Info {
+		_, err = fmt.Fprintf(dst, "\n// Modification time of %s\nvar %s_ModTime = %s\n", name, sanitised, timeExpr(modTime))
+		if err != nil {
+			return err
+		}
+
+		dst.Import("time")
+	}
+
+	if opts.Mode && !opts.Info {
+		_, err = fmt.Fprintf(dst, "\n// Permission bits of %s\nconst %s_Mode = %#o\n", name, sanitised, mode)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.Info {
+		if err = writeInfo(dst, name, sanitised, size, modTime, mode, contentType); err != nil {
+			return err
+		}
+	}
+
+	dst.Files = append(dst.Files, file)
+	return err
+}
+
+// derived returns the suffixes of the identifiers
+// that Embed derives from each file's, given opts,
+// so that they're reserved along with it.
+func derived(opts *Options) []string {
+	var suffixes []string
+	add := func(ok bool, suffix ...string) {
+		if ok {
+			suffixes = append(suffixes, suffix...)
+		}
+	}
+
+	switch {
+	case opts.Encoding != nil:
+		add(true, opts.Encoding.Suffix, "_once", "_data", "_err")
+	case opts.Compression != nil:
+		add(true, opts.Compression.Suffix, "_raw", "_once", "_data", "_err")
+	}
+
+	add(opts.String || opts.Raw || opts.Lines, "_Bytes")
+	add(opts.Reader, "_Reader")
+	add(opts.BytesReader, "_BytesReader")
+	for _, alg := range opts.Hashes {
+		add(true, "_"+Hashes[alg].Suffix)
+	}
+
+	add(opts.CRC32, "_CRC32")
+	add(opts.Size || opts.Compression != nil, "_Size", "_GzipSize")
+	add((opts.SmartCompress || opts.MinCompressSize > 0) && opts.Compression != nil, "_Compressed")
+	add(opts.MIME, "_ContentType")
+	add(opts.ModTime, "_ModTime")
+	add(opts.Mode, "_Mode")
+	add(opts.Info, "_Info")
+	return suffixes
+}
+
+// timeExpr returns a Go expression for t.
+func timeExpr(t time.Time) string {
+	if t.IsZero() {
+		return "time.Time{}"
+	}
+
+	return fmt.Sprintf("time.Unix(%d, %d)", t.Unix(), t.Nanosecond())
+}
+
+// firstPass writes the data read from src to w,
+// returning a reader for the same data: src
+// rewound, if it can be, or else a copy.
+func firstPass(src io.Reader, w io.Writer) (io.Reader, error) {
+	if s, ok := src.(io.ReadSeeker); ok {
+		if start, err := s.Seek(0, io.SeekCurrent); err == nil {
+			if _, err = io.Copy(w, s); err != nil {
+				return nil, err
+			}
+
+			_, err = s.Seek(start, io.SeekStart)
+			return s, err
+		}
+	}
+
+	content, err := io.ReadAll(io.TeeReader(src, w))
+	return bytes.NewReader(content), err
+}
+
+// writeHashes writes the hashes of name's
+// contents, and its CRC-32 checksum with CRC32.
+func writeHashes(dst *Output, name, sanitised string, hashers []hash.Hash, checksum hash.Hash32) error {
+	var err error
+	for i, hasher := range hashers {
+		suffix := Hashes[dst.opts.Hashes[i]].Suffix
+		if dst.opts.HashFormat == "hex" {
+			_, err = fmt.Fprintf(dst, "\n// %s hash of %s\nconst %s_%s = \"%x\"\n", suffix, name, sanitised, suffix, hasher.Sum(nil))
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		typ := "[]byte"
+		if dst.opts.Array {
+			typ = fmt.Sprintf("[%d]byte", hasher.Size())
+		}
+
+		_, err = fmt.Fprintf(dst, "\n// %s hash of %s\nvar %s_%s = %s{", suffix, name, sanitised, suffix, typ)
+		if err != nil {
+			return err
+		}
+
+		w := &byteSliceWriter{w: dst, width: dst.opts.Width}
+		if _, err = w.Write(hasher.Sum(nil)); err != nil {
+			return err
+		}
+
+		if err = w.Close(); err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(dst, "}\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	if dst.opts.CRC32 {
+		_, err = fmt.Fprintf(dst, "\n// CRC-32 checksum of %s\nconst %s_CRC32 = %#08x\n", name, sanitised, checksum.Sum32())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeDecodeAccessor writes the function that
+// lazily decodes the constant for name with the
+// given identifier, decompressing it too if it's
+// compressed.
+func writeDecodeAccessor(dst *Output, name, literal string, compressed bool) error {
+	dst.opts.Encoding.use(dst)
+	dst.Import("sync")
+
+	decode := `var err error
+		%[1]s_data, err = ` + fmt.Sprintf(dst.opts.Encoding.Decode, literal) + `
+		if err != nil {
+			panic(err)
+		}`
+
+	if compressed {
+		dst.Import("bytes")
+		dst.Import("io")
+		decode = `b, err := ` + fmt.Sprintf(dst.opts.Encoding.Decode, literal) + `
+		if err != nil {
+			panic(err)
+		}
+
+		var r io.Reader
+		if r, err = ` + decompress(dst, "bytes.NewReader(b)") + `; err != nil {
+			panic(err)
+		}
+
+		var buf bytes.Buffer
+		if _, err = buf.ReadFrom(r); err != nil {
+			panic(err)
+		}
+
+		%[1]s_data = buf.Bytes()`
+	}
+
+	_, err := fmt.Fprintf(dst, `
+var (
+	%[1]s_once sync.Once
+	%[1]s_data []byte
+)
+
+func %[1]s() []byte {
+	%[1]s_once.Do(func() {
+		`+decode+`
+	})
+
+	return %[1]s_data
+}
+`, name)
+	return err
+}
+
+// writeDecompressAccessor writes the function that
+// lazily decompresses the data for name, read
+// using the given expression.
+func writeDecompressAccessor(dst *Output, name, reader string) error {
+	dst.Import("bytes")
+	dst.Import("io")
+	dst.Import("sync")
+
+	_, err := fmt.Fprintf(dst, `
+var (
+	%[1]s_once sync.Once
+	%[1]s_data []byte
+	%[1]s_err  error
+)
+
+func %[1]s() ([]byte, error) {
+	%[1]s_once.Do(func() {
+		var r io.Reader
+		r, %[1]s_err = %[2]s
+		if %[1]s_err != nil {
+			return
+		}
+
+		var buf bytes.Buffer
+		_, %[1]s_err = buf.ReadFrom(r)
+		%[1]s_data = buf.Bytes()
+	})
+
+	return %[3]s, %[1]s_err
+}
+`, name, decompress(dst, reader), copied(dst, name+"_data"))
+	return err
+}
+
+// writeStoredAccessor writes the function, with
+// the same signature as a decompressing accessor,
+// that returns the uncompressed data for name
+// given by the expression.
+func writeStoredAccessor(dst *Output, name, data string) error {
+	_, err := fmt.Fprintf(dst, "\nfunc %s() ([]byte, error) {\n\treturn %s, nil\n}\n", name, copied(dst, data))
+	return err
+}
+
+// copied returns an expression yielding a copy
+// of the []byte data with CopyAccessor, so that
+// callers can't modify the embedded data, or
+// else the data itself.
+func copied(dst *Output, data string) string {
+	if !dst.opts.CopyAccessor {
+		return data
+	}
+
+	return "append([]byte(nil), " + data + "...)"
+}
+
+// writeReaderAccessor writes the function that
+// returns a reader for name's contents, which
+// decompresses the data read using the given
+// expression on the fly if it's compressed.
+func writeReaderAccessor(dst *Output, name, reader string, compressed bool) error {
+	dst.Import("io")
+	if dst.opts.Compression == nil {
+		_, err := fmt.Fprintf(dst, "\nfunc %s_Reader() io.Reader {\n\treturn %s\n}\n", name, reader)
+		return err
+	}
+
+	if !compressed {
+		_, err := fmt.Fprintf(dst, "\nfunc %s_Reader() (io.Reader, error) {\n\treturn %s, nil\n}\n", name, reader)
+		return err
+	}
+
+	_, err := fmt.Fprintf(dst, "\nfunc %s_Reader() (io.Reader, error) {\n\treturn %s\n}\n", name, decompress(dst, reader))
+	return err
+}
+
+// writeBytesReaderAccessor writes the function
+// that returns a *bytes.Reader for the file's
+// contents, which can seek and read at offsets,
+// unlike the io.Reader from writeReaderAccessor.
+func writeBytesReaderAccessor(dst *Output, file File) error {
+	dst.Import("bytes")
+	if !file.Fallible {
+		_, err := fmt.Fprintf(dst, "\nfunc %s_BytesReader() *bytes.Reader {\n\treturn bytes.NewReader(%s)\n}\n", file.Ident, file.Value)
+		return err
+	}
+
+	_, err := fmt.Fprintf(dst, "\nfunc %s_BytesReader() (*bytes.Reader, error) {\n\tb, err := %s\n\tif err != nil {\n\t\treturn nil, err\n\t}\n\n\treturn bytes.NewReader(b), nil\n}\n", file.Ident, file.Value)
+	return err
+}
+
+// DefaultWidth is the default number of bytes written
+// to each line of a []byte literal.
+const DefaultWidth = 12
+
+// byteSliceWriter writes data as the elements of a
+// []byte literal, width bytes to a line. Close
+// writes any partial final line. The elements start
+// on a new line, unless there are none, so that an
+// empty slice is written as []byte{}. Lines are
+// only written once they're full, or on Close, so
+// data whose size is a multiple of width ends with
+// a full line rather than an empty one, however
+// the writes are split. Each element is written as
+// two hex digits and followed by a comma, the last
+// included, so the columns line up as they are,
+// gofmt leaves them alone, and changing a byte
+// only changes its own line in a diff.
+type byteSliceWriter struct {
+	w      io.Writer
+	width  int
+	buf    []byte // Partial line.
+	line   []byte // Scratch space for formatting lines.
+	lines  int
+	group  int   // Lines in each block, as for Pretty, or 0.
+	offset int64 // Offset of the next line, for the blocks' comments.
+}
+
+func (b *byteSliceWriter) Write(p []byte) (n int, err error) {
+	n = len(p)
+	if len(b.buf) > 0 {
+		m := b.width - len(b.buf)
+		if m > len(p) {
+			m = len(p)
+		}
+
+		b.buf = append(b.buf, p[:m]...)
+		p = p[m:]
+
+		if len(b.buf) < b.width {
+			return n, nil
+		}
+
+		if err = b.writeLine(b.buf); err != nil {
+			return n - len(p), err
+		}
+
+		b.buf = b.buf[:0]
+	}
+
+	for len(p) >= b.width {
+		if err = b.writeLine(p[:b.width]); err != nil {
+			return n - len(p), err
+		}
+
+		p = p[b.width:]
+	}
+
+	b.buf = append(b.buf, p...)
+	return n, nil
+}
+
+func (b *byteSliceWriter) Close() error {
+	if len(b.buf) == 0 {
+		return nil
+	}
+
+	err := b.writeLine(b.buf)
+	b.buf = b.buf[:0]
+	return err
+}
+
+const hexDigits = "0123456789abcdef"
+
+// writeLine writes data as a single line of
+// elements, formatted without fmt as this is
+// called for every line of every file.
+func (b *byteSliceWriter) writeLine(data []byte) error {
+	line := b.line[:0]
+	if b.lines == 0 {
+		line = append(line, '\n')
+	}
+
+	if b.group > 0 && b.lines%b.group == 0 {
+		if b.lines > 0 {
+			line = append(line, '\n')
+		}
+
+		off := strconv.AppendInt(nil, b.offset, 16)
+		line = append(line, "\t// 0x"...)
+		for i := len(off); i < 4; i++ {
+			line = append(line, '0')
+		}
+
+		line = append(append(line, off...), '\n')
+	}
+
+	b.offset += int64(len(data))
+	line = append(line, '\t')
+	for i, c := range data {
+		if i > 0 {
+			line = append(line, ' ')
+		}
+
+		line = append(line, '0', 'x', hexDigits[c>>4], hexDigits[c&0x0f], ',')
+	}
+
+	line = append(line, '\n')
+	b.line = line
+	b.lines++
+
+	_, err := b.w.Write(line)
+	return err
+}
+
+// isRaw reports whether data can be written
+// as a raw string literal. Raw strings can't
+// contain backticks, and carriage returns are
+// discarded from them, so either rules it out,
+// as does anything the compiler won't accept
+// in source.
+func isRaw(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+
+	for _, r := range string(data) {
+		switch {
+		case r == '`', r == '\r', r == '\uFEFF':
+			return false
+		case r == '\t', r == '\n':
+		case r < 0x20, r == 0x7f:
+			return false
+		}
+	}
+
+	return true
+}
+
+// linesWriter writes text as the elements of a
+// []string literal, one to each line of text.
+// Close writes any final line that doesn't end
+// in a newline.
+type linesWriter struct {
+	w       io.Writer
+	line    []byte
+	lines   int
+	newline bool // Whether the text ends in a newline.
+}
+
+func (l *linesWriter) Write(b []byte) (int, error) {
+	n := len(b)
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			l.line = append(l.line, b...)
+			l.newline = false
+			break
+		}
+
+		l.line = append(l.line, b[:i]...)
+		if err := l.flush(); err != nil {
+			return 0, err
+		}
+
+		l.newline = true
+		b = b[i+1:]
+	}
+
+	return n, nil
+}
+
+func (l *linesWriter) flush() error {
+	_, err := fmt.Fprintf(l.w, "\n\t%s,", strconv.Quote(string(l.line)))
+	l.line = l.line[:0]
+	l.lines++
+	return err
+}
+
+func (l *linesWriter) Close() error {
+	if len(l.line) > 0 {
+		if err := l.flush(); err != nil {
+			return err
+		}
+	}
+
+	if l.lines > 0 {
+		_, err := io.WriteString(l.w, "\n")
+		return err
+	}
+
+	return nil
+}
+
+// progressReader passes the number of bytes read
+// from the named file to add, as they're read.
+type progressReader struct {
+	r    io.Reader
+	name string
+	add  func(name string, n int)
+}
+
+func (p progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.add(p.name, n)
+	return n, err
+}
+
+// nopCloser writes data unchanged.
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// stringWriter writes data as the contents of an
+// interpreted string literal. Printable ASCII is
+// written as-is and everything else is escaped.
+type stringWriter struct {
+	w io.Writer
+}
+
+func (s *stringWriter) Write(p []byte) (int, error) {
+	var w bytes.Buffer
+	for _, c := range p {
+		switch c {
+		case '"', '\\':
+			w.WriteByte('\\')
+			w.WriteByte(c)
+		case '\n':
+			w.WriteString(`\n`)
+		case '\r':
+			w.WriteString(`\r`)
+		case '\t':
+			w.WriteString(`\t`)
+		default:
+			if c < 0x20 || c >= 0x7f {
+				fmt.Fprintf(&w, "\\x%02x", c)
+			} else {
+				w.WriteByte(c)
+			}
+		}
+	}
+
+	if _, err := s.w.Write(w.Bytes()); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func (s *stringWriter) Close() error {
+	return nil
+}
+
+// exported returns an exported form of the
+// identifier, by capitalising its first letter.
+// Leading underscores are dropped, and if that
+// doesn't leave a letter that can be capitalised,
+// the identifier is prefixed with X instead.
+func exported(ident string) string {
+	s := strings.TrimLeft(ident, "_")
+	r, n := utf8.DecodeRuneInString(s)
+	if unicode.IsLetter(r) && unicode.IsUpper(unicode.ToUpper(r)) {
+		return string(unicode.ToUpper(r)) + s[n:]
+	}
+
+	return "X" + ident
+}
+
+// trimPrefix removes prefix from the start of
+// name, regardless of the path separator.
+func trimPrefix(name, prefix string) string {
+	if prefix == "" {
+		return name
+	}
+
+	return strings.TrimPrefix(filepath.ToSlash(name), filepath.ToSlash(prefix))
+}
+
+// sanitise returns an identifier for name, replacing
+// any characters that aren't valid in identifiers
+// with underscores, and prefixing one to a leading
+// digit, keyword or predeclared identifier.
+func sanitise(name string) string {
+	var buf bytes.Buffer
+	var first = true
+
+	for len(name) > 0 {
+		r, n := utf8.DecodeRuneInString(name)
+		switch {
+		case unicode.IsLetter(r) || (!first && unicode.IsNumber(r)):
+			buf.WriteRune(r)
+		case first && unicode.IsNumber(r):
+			// A leading digit is kept, after an
+			// underscore, so 1.png and 2.png
+			// stay distinct.
+
+			buf.WriteByte('_')
+			buf.WriteRune(r)
+		default:
+			buf.WriteByte('_')
+		}
+
+		first = false
+		name = name[n:]
+	}
+
+	if buf.Len() == 0 {
+		buf.WriteByte('_')
+	}
+
+	// Keywords are invalid identifiers and
+	// predeclared identifiers would be shadowed.
+
+	ident := buf.String()
+	if token.IsKeyword(ident) || predeclared[ident] {
+		ident = "_" + ident
+	}
+
+	return ident
+}
+
+// predeclared contains Go's predeclared identifiers.
+var predeclared = map[string]bool{
+	"any": true, "bool": true, "byte": true, "comparable": true,
+	"complex64": true, "complex128": true, "error": true, "float32": true,
+	"float64": true, "int": true, "int8": true, "int16": true,
+	"int32": true, "int64": true, "rune": true, "string": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true,
+	"uint64": true, "uintptr": true,
+
+	"true": true, "false": true, "iota": true, "nil": true,
+
+	"append": true, "cap": true, "clear": true, "close": true,
+	"complex": true, "copy": true, "delete": true, "imag": true,
+	"len": true, "make": true, "max": true, "min": true,
+	"new": true, "panic": true, "print": true, "println": true,
+	"real": true, "recover": true,
+}
@@ -0,0 +1,851 @@
+package embed
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// render embeds each of files, by name, using
+// opts, and returns the rendered source.
+func render(t *testing.T, opts Options, files ...[2]string) []byte {
+	t.Helper()
+	if opts.Package == "" {
+		opts.Package = "p"
+	}
+
+	out := NewOutput(opts)
+	for _, file := range files {
+		in := Input{Name: file[0], Ident: file[0]}
+		if err := Embed(out, strings.NewReader(file[1]), in); err != nil {
+			t.Fatalf("Embed(%q): %v", file[0], err)
+		}
+	}
+
+	if err := Finish(out); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+
+	src, err := Render(out)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	return src
+}
+
+// compile type-checks src, failing the test if it
+// doesn't compile.
+func compile(t *testing.T, src []byte) *types.Package {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "out.go", src, 0)
+	if err != nil {
+		t.Fatalf("failed to parse:\n%s\n%v", src, err)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := conf.Check("p", fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatalf("failed to compile:\n%s\n%v", src, err)
+	}
+
+	return pkg
+}
+
+func TestRender(t *testing.T) {
+	// The example in the package documentation.
+
+	out := NewOutput(Options{Package: "assets", Map: "Files"})
+	if err := Embed(out, strings.NewReader("<html></html>"), Input{Name: "index.html", Ident: "index.html"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Finish(out); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := Render(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkg := compile(t, src)
+	if pkg.Name() != "assets" {
+		t.Errorf("got package %s, want assets", pkg.Name())
+	}
+
+	for _, name := range []string{"index_html", "Files"} {
+		if pkg.Scope().Lookup(name) == nil {
+			t.Errorf("got:\n%s\nwant it to declare %s", src, name)
+		}
+	}
+
+	if len(out.Files) != 1 || out.Files[0].Path != "index.html" {
+		t.Errorf("got files %v, want index.html", out.Files)
+	}
+}
+
+func TestIdentCollisions(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  Options
+		files []string
+	}{
+		{"sanitised", Options{}, []string{"a-b.txt", "a.b.txt", "a_b.txt"}},
+		{"hash", Options{Hashes: []string{"sha1"}}, []string{"x", "x_SHA1"}},
+		{"hash reversed", Options{Hashes: []string{"sha1"}}, []string{"x_SHA1", "x"}},
+		{"compressed", Options{Compression: Compressors["gzip"], Reader: true}, []string{"x_gz", "x", "x_Reader", "x_once"}},
+		{"encoded", Options{Encoding: Encodings["base64"]}, []string{"x", "x_b64", "x_data"}},
+		{"string", Options{String: true, Size: true}, []string{"x_Bytes", "x", "x_Size"}},
+		{"chunks", Options{Chunk: 1}, []string{"x_0", "x", "x_1"}},
+		{"string chunks", Options{String: true, ChunkString: 1}, []string{"x_0", "x", "x_1"}},
+		{"copy", Options{CopyAccessor: true}, []string{"_x", "x"}},
+		{"map", Options{Map: "M", HashedNames: true, Assets: true}, []string{"M", "M_Hashed", "Asset", "AssetNames"}},
+		{"struct", Options{Struct: "S"}, []string{"S"}},
+		{"fs", Options{FS: "F"}, []string{"F", "F_FS", "F_entry", "F_modes", "F_modTimes", "F_info", "F_dirEntry"}},
+		{"http fs", Options{HTTPFS: "H"}, []string{"H", "H_HTTPFS", "H_httpModTimes", "H_httpFile"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			files := make([][2]string, len(test.files))
+			for i, name := range test.files {
+				files[i] = [2]string{name, name + " contents"}
+			}
+
+			compile(t, render(t, test.opts, files...))
+		})
+	}
+}
+
+func TestAppendImports(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+	}{
+		{"none", "package p\n\nvar x = 1\n"},
+		{"single", "package p\n\nimport \"strings\"\n\nvar x = strings.ToLower\n"},
+		{"block", "package p\n\nimport (\n\t\"strings\"\n)\n\nvar x = strings.ToLower\n"},
+		{"one line", "package p\n\nimport (\"strings\")\n\nvar x = strings.ToLower\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), "out.go")
+			if err := os.WriteFile(name, []byte(test.existing), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			out := NewOutput(Options{Package: "p", Compression: Compressors["gzip"]})
+			if err := LoadExisting(out, name); err != nil {
+				t.Fatalf("LoadExisting: %v", err)
+			}
+
+			if err := Embed(out, strings.NewReader("data"), Input{Name: "y", Ident: "y"}); err != nil {
+				t.Fatalf("Embed: %v", err)
+			}
+
+			src, err := Render(out)
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+
+			if n := bytes.Count(src, []byte("import")); n != 1 {
+				t.Errorf("got %d import declarations, want 1:\n%s", n, src)
+			}
+
+			compile(t, src)
+		})
+	}
+}
+
+func TestDuplicatesCompressed(t *testing.T) {
+	opts := Options{Compression: Compressors["gzip"], Level: 9, SmartCompress: true, Map: "M"}
+	contents := strings.Repeat("same contents ", 100)
+	src := render(t, opts, [2]string{"same.png", contents}, [2]string{"same.txt", contents})
+	if bytes.Contains(src, []byte("same_txt_gz = same_png_raw")) {
+		t.Errorf("compressed data refers to uncompressed data:\n%s", src)
+	}
+
+	compile(t, src)
+}
+
+func TestByteSliceWriter(t *testing.T) {
+	tests := []struct {
+		size int
+		want string
+	}{
+		{0, ""},
+		{11, "\n" +
+			"\t0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a,\n"},
+		{12, "\n" +
+			"\t0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b,\n"},
+		{13, "\n" +
+			"\t0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b,\n" +
+			"\t0x0c,\n"},
+		{24, "\n" +
+			"\t0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b,\n" +
+			"\t0x0c, 0x0d, 0x0e, 0x0f, 0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17,\n"},
+	}
+
+	// Each input is written whole, and split into
+	// writes of each of these sizes.
+
+	splits := []int{0, 1, 5, 11, 12, 13}
+	for _, test := range tests {
+		data := make([]byte, test.size)
+		for i := range data {
+			data[i] = byte(i)
+		}
+
+		for _, split := range splits {
+			var buf bytes.Buffer
+			w := &byteSliceWriter{w: &buf, width: DefaultWidth}
+			for p := data; len(p) > 0; {
+				n := len(p)
+				if split > 0 && split < n {
+					n = split
+				}
+
+				if _, err := w.Write(p[:n]); err != nil {
+					t.Fatal(err)
+				}
+
+				p = p[n:]
+			}
+
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := buf.String(); got != test.want {
+				t.Errorf("%d bytes, split by %d: got\n%q\nwant\n%q", test.size, split, got, test.want)
+			}
+		}
+	}
+}
+
+func TestOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"default", Options{}, "var x = []byte{\n\t0x64, 0x61, 0x74, 0x61,\n}\n"},
+		{"width", Options{Width: 2}, "var x = []byte{\n\t0x64, 0x61,\n\t0x74, 0x61,\n}\n"},
+		{"string", Options{String: true}, "var x = \"data\"\n"},
+		{"const", Options{String: true, Const: true}, "const x = \"data\"\n"},
+		{"base64", Options{Encoding: Encodings["base64"]}, "const x_b64 = \"ZGF0YQ==\"\n"},
+		{"gzip", Options{Compression: Compressors["gzip"], Level: 9}, "var x_gz = []byte{\n\t0x1f, 0x8b,"},
+		{"hash", Options{Hashes: []string{"sha1"}, HashFormat: "hex"}, "const x_SHA1 = \"a17c9aaa61e80a1bf71d0d850af4e5baa9800bbd\"\n"},
+		{"size", Options{Size: true}, "const x_Size = 4\n"},
+		{"prefix", Options{Prefix: "asset_"}, "var asset_x = []byte{"},
+		{"export", Options{Export: true}, "var X = []byte{"},
+		{"map", Options{Map: "Files"}, "var Files = map[string][]byte{\n\t\"x\": x,\n}\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			src := render(t, test.opts, [2]string{"x", "data"})
+			if !bytes.Contains(src, []byte(test.want)) {
+				t.Errorf("got:\n%s\nwant it to contain:\n%s", src, test.want)
+			}
+
+			compile(t, src)
+		})
+	}
+}
+
+func TestOutputsIndependent(t *testing.T) {
+	// Outputs with different options don't affect
+	// each other, even when used together.
+
+	str := NewOutput(Options{Package: "p", String: true})
+	hex := NewOutput(Options{Package: "p", Hashes: []string{"sha1"}, HashFormat: "hex"})
+	for _, out := range []*Output{str, hex, str} {
+		name := fmt.Sprintf("f%d", len(out.Files))
+		if err := Embed(out, strings.NewReader("data"), Input{Name: name, Ident: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, out := range []*Output{str, hex} {
+		src, err := Render(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		compile(t, src)
+	}
+
+	if len(str.Files) != 2 || len(hex.Files) != 1 {
+		t.Errorf("got %d and %d files, want 2 and 1", len(str.Files), len(hex.Files))
+	}
+}
+
+func TestKeywordNames(t *testing.T) {
+	for _, name := range []string{"func", "type", "package", "range"} {
+		if got, want := sanitise(name), "_"+name; got != want {
+			t.Errorf("sanitise(%q) = %q, want %q", name, got, want)
+		}
+
+		src := render(t, Options{Hashes: []string{"sha1"}}, [2]string{name, "data"})
+		compile(t, src)
+		if want := "var _" + name + "_SHA1 = "; !bytes.Contains(src, []byte(want)) {
+			t.Errorf("%s: got:\n%s\nwant it to contain %q", name, src, want)
+		}
+	}
+}
+
+func TestEmptyFile(t *testing.T) {
+	src := render(t, Options{Hashes: []string{"sha1"}, HashFormat: "hex"}, [2]string{"empty", ""})
+	compile(t, src)
+	for _, want := range []string{
+		"var empty = []byte{}\n",
+		"const empty_SHA1 = \"da39a3ee5e6b4b0d3255bfef95601890afd80709\"\n",
+	} {
+		if !bytes.Contains(src, []byte(want)) {
+			t.Errorf("got:\n%s\nwant it to contain:\n%s", src, want)
+		}
+	}
+}
+
+func BenchmarkEmbed(b *testing.B) {
+	data := make([]byte, 10<<20)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		out := NewOutput(Options{Package: "p"})
+		if err := Embed(out, bytes.NewReader(data), Input{Name: "data", Ident: "data"}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestTranscode(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		data     string
+		want     string
+	}{
+		{"utf-16le bom", "utf-16le", "utf-8", "\xff\xfeh\x00i\x00", `"hi"`},
+		{"utf-16be bom", "utf-16be", "utf-8", "\xfe\xff\x00h\x00i", `"hi"`},
+		{"utf-16 bom", "utf-16", "utf-8", "\xff\xfeh\x00i\x00", `"hi"`},
+		{"utf-8 bom", "utf-8", "iso-8859-1", "\xef\xbb\xbfh\xc3\xa9", `"h\xe9"`},
+		{"latin1", "latin1", "utf-8", "h\xe9", `"h\xc3\xa9"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := Options{String: true, TranscodeFrom: Charsets[test.from], TranscodeTo: Charsets[test.to]}
+			src := render(t, opts, [2]string{"x.txt", test.data})
+			if want := "var x_txt = " + test.want + "\n"; !bytes.Contains(src, []byte(want)) {
+				t.Errorf("got:\n%s\nwant it to contain:\n%s", src, want)
+			}
+		})
+	}
+
+	// Binary files are left alone.
+
+	opts := Options{TranscodeFrom: Charsets["utf-16le"], TranscodeTo: Charsets["utf-8"]}
+	compile(t, render(t, opts, [2]string{"x.bin", "odd"}))
+}
+
+func TestGzipSize(t *testing.T) {
+	gz := Compressors["gzip"]
+	tests := []struct {
+		name string
+		opts Options
+		want bool
+	}{
+		{"gzip", Options{Compression: gz, Level: 9}, true},
+		{"zlib", Options{Compression: Compressors["zlib"], Level: 9}, false},
+		{"base64", Options{Compression: gz, Level: 9, Encoding: Encodings["base64"]}, false},
+		{"ascii85", Options{Compression: gz, Level: 9, Encoding: Encodings["ascii85"]}, false},
+	}
+
+	for _, test := range tests {
+		src := render(t, test.opts, [2]string{"x", strings.Repeat("data", 100)})
+		if got := bytes.Contains(src, []byte("x_GzipSize")); got != test.want {
+			t.Errorf("%s: got x_GzipSize %t, want %t:\n%s", test.name, got, test.want, src)
+		}
+	}
+}
+
+func TestSanitise(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"index.html", "index_html"},
+		{"a-b.txt", "a_b_txt"},
+		{".env", "_env"},
+		{"1.png", "_1_png"},
+		{"2.png", "_2_png"},
+		{"123.png", "_123_png"},
+		{"9lives.txt", "_9lives_txt"},
+		{"", "_"},
+	}
+
+	for _, test := range tests {
+		if got := sanitise(test.name); got != test.want {
+			t.Errorf("sanitise(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestASCIINames(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"caf\u00e9.txt", "cafe_txt"},
+		{"cafe\u0301.txt", "cafe_txt"},
+		{"Ærøskøbing.png", "AEroskobing_png"},
+		{"straße.html", "strasse_html"},
+		{"日本.txt", "u65e5u672c_txt"},
+		{"index.html", "index_html"},
+	}
+
+	for _, test := range tests {
+		src := render(t, Options{ASCIINames: true}, [2]string{test.name, "data"})
+		if want := "var " + test.want + " = "; !bytes.Contains(src, []byte(want)) {
+			t.Errorf("%s: got:\n%s\nwant it to contain %q", test.name, src, want)
+		}
+	}
+
+	// Unicode letters are kept by default.
+
+	src := render(t, Options{}, [2]string{"café.txt", "data"}, [2]string{"日本.txt", "data"})
+	for _, want := range []string{"var café_txt = ", "var 日本_txt = "} {
+		if !bytes.Contains(src, []byte(want)) {
+			t.Errorf("got:\n%s\nwant it to contain %q", src, want)
+		}
+	}
+}
+
+func TestGoEmbed(t *testing.T) {
+	out := NewOutput(Options{Package: "p", GoEmbed: "static", Map: "Files"})
+	for _, name := range []string{"index.html", "../img/logo.png"} {
+		if err := Embed(out, strings.NewReader(name), Input{Name: name, Ident: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := Finish(out); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := Render(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"// index.html\n//\n//go:embed static/index.html\nvar index_html []byte\n",
+		"//go:embed static/img/logo.png\nvar ___img_logo_png []byte\n",
+		"//go:embed static/index.html static/img/logo.png\nvar StaticFS embed.FS\n",
+		"\t\"index.html\":      index_html,\n",
+	} {
+		if !bytes.Contains(src, []byte(want)) {
+			t.Errorf("got:\n%s\nwant it to contain:\n%s", src, want)
+		}
+	}
+
+	for i, want := range []string{"static/index.html", "static/img/logo.png"} {
+		if file := out.Files[i]; file.EmbedPath != want || string(file.Data) != file.Path {
+			t.Errorf("file %d: got %q with %q, want %q with %q", i, file.EmbedPath, file.Data, want, file.Path)
+		}
+	}
+}
+
+func TestLineDirectives(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"comments", Options{}, "// a.txt\n//\n//line a.txt:1\nvar a_txt = []byte{\n"},
+		{"no comments", Options{NoComments: true}, "\n\n//line a.txt:1\nvar a_txt = []byte{\n"},
+		{"docs", Options{Docs: true}, "// a_txt holds the embedded contents of a.txt (4 bytes).\n//\n//line a.txt:1\nvar a_txt = []byte{\n"},
+		{"goembed", Options{GoEmbed: "static"}, "// a.txt\n//\n//line a.txt:1\n//go:embed static/a.txt\nvar a_txt []byte\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.opts.LineDirectives = true
+			src := render(t, test.opts, [2]string{"a.txt", "data"})
+			if !bytes.Contains(src, []byte(test.want)) {
+				t.Errorf("got:\n%s\nwant it to contain:\n%s", src, test.want)
+			}
+
+			// The directives must survive formatting
+			// unchanged.
+
+			formatted, err := format.Source(src)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(formatted, src) {
+				t.Errorf("formatting changed:\n%s\nto:\n%s", src, formatted)
+			}
+
+			if test.opts.GoEmbed == "" {
+				compile(t, src)
+			}
+		})
+	}
+}
+
+func TestBytesReader(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"bytes", Options{}, "func a_txt_BytesReader() *bytes.Reader {\n\treturn bytes.NewReader(a_txt)\n}\n"},
+		{"string", Options{String: true}, "func a_txt_BytesReader() *bytes.Reader {\n\treturn bytes.NewReader([]byte(a_txt))\n}\n"},
+		{"array", Options{Array: true}, "func a_txt_BytesReader() *bytes.Reader {\n\treturn bytes.NewReader(a_txt[:])\n}\n"},
+		{"gzip", Options{Compression: Compressors["gzip"], Level: 9}, "func a_txt_BytesReader() (*bytes.Reader, error) {\n\tb, err := a_txt()\n\tif err != nil {\n\t\treturn nil, err\n\t}\n\n\treturn bytes.NewReader(b), nil\n}\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.opts.BytesReader = true
+			src := render(t, test.opts, [2]string{"a.txt", strings.Repeat("data", 100)})
+			if !bytes.Contains(src, []byte(test.want)) {
+				t.Errorf("got:\n%s\nwant it to contain:\n%s", src, test.want)
+			}
+
+			compile(t, src)
+		})
+	}
+}
+
+func TestCache(t *testing.T) {
+	opts := Options{Compression: Compressors["gzip"], Level: 9, Hashes: []string{"sha1"}, Map: "Files"}
+	files := [][2]string{
+		{"a.txt", strings.Repeat("a", 100)},
+		{"b.txt", strings.Repeat("b", 100)},
+		{"c.txt", strings.Repeat("a", 100)},
+	}
+
+	want := render(t, opts, files...)
+	opts.Cache = NewCache("key")
+	if got := render(t, opts, files...); !bytes.Equal(got, want) {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	data, err := opts.Cache.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Duplicates aren't cached.
+
+	opts.Cache = DecodeCache(data, "key")
+	if len(opts.Cache.old) != 2 {
+		t.Fatalf("got %d cached files, want 2", len(opts.Cache.old))
+	}
+
+	if got := render(t, opts, files...); !bytes.Equal(got, want) {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	for key, f := range opts.Cache.old {
+		if opts.Cache.used[key] != f {
+			t.Errorf("%s wasn't copied from the cache", f.File.Path)
+		}
+	}
+
+	// Files whose identifiers or data are now
+	// taken by an earlier file must be embedded
+	// again.
+
+	changed := [][2]string{{"a.txt", "other"}, files[1], files[0]}
+	opts.Cache = DecodeCache(data, "key")
+	got := render(t, opts, changed...)
+	opts.Cache = nil
+	if want := render(t, opts, changed...); !bytes.Equal(got, want) {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	for _, key := range []string{"other", ""} {
+		if c := DecodeCache(data, key); len(c.old) != 0 {
+			t.Errorf("DecodeCache with key %q: got %d cached files, want none", key, len(c.old))
+		}
+	}
+}
+
+func TestFuncs(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{"bytes", Options{}, []string{
+			"func a_txt() []byte {\n\treturn []byte{\n\t\t0x64, 0x61, 0x74, 0x61,\n\t}\n}\n",
+			"func b_txt() []byte {\n\treturn a_txt()\n}\n",
+			"\t\"a.txt\": a_txt(),\n",
+		}},
+		{"gzip", Options{Compression: Compressors["gzip"], Level: 9}, []string{
+			"func a_txt_gz() []byte {\n\treturn []byte{\n",
+			"func b_txt_gz() []byte {\n\treturn a_txt_gz()\n}\n",
+		}},
+		{"docs", Options{Docs: true}, []string{
+			"// a_txt returns the embedded contents of a.txt (4 bytes).\nfunc a_txt() []byte {\n",
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.opts.Funcs = true
+			test.opts.Map = "Files"
+			test.opts.BytesReader = true
+			src := render(t, test.opts, [2]string{"a.txt", "data"}, [2]string{"b.txt", "data"})
+			for _, want := range test.want {
+				if !bytes.Contains(src, []byte(want)) {
+					t.Errorf("got:\n%s\nwant it to contain:\n%s", src, want)
+				}
+			}
+
+			compile(t, src)
+		})
+	}
+}
+
+func TestWriteTest(t *testing.T) {
+	out := NewOutput(Options{Package: "p", Hashes: []string{"sha1"}, HashFormat: "hex", CRC32: true})
+	for _, name := range []string{"logo.png", "Logo.png"} {
+		if err := Embed(out, strings.NewReader(name), Input{Name: name, Ident: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	test, err := WriteTest(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := Render(test)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"func TestLogo_pngCRC32(t *testing.T) {",
+		"func TestLogo_pngSHA1(t *testing.T) {",
+		"func TestLogo_pngCRC32_2(t *testing.T) {",
+		"func TestLogo_pngSHA1_2(t *testing.T) {",
+	} {
+		if !bytes.Contains(src, []byte(want)) {
+			t.Errorf("got:\n%s\nwant it to contain %q", src, want)
+		}
+	}
+}
+
+func TestPretty(t *testing.T) {
+	want := "\n\t// 0x0000\n\t0x00, 0x01,\n\t0x02, 0x03,\n\n\t// 0x0004\n\t0x04,\n"
+	var buf bytes.Buffer
+	w := &byteSliceWriter{w: &buf, width: 2, group: 2}
+	if _, err := w.Write([]byte{0, 1, 2, 3, 4}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+
+	// Chunks give the offsets in the whole file.
+
+	src := render(t, Options{Pretty: 1, Width: 4, Chunk: 4}, [2]string{"a.txt", "0123456789"})
+	for _, want := range []string{"var a_txt_1 = []byte{\n\t// 0x0004\n", "var a_txt_2 = []byte{\n\t// 0x0008\n"} {
+		if !bytes.Contains(src, []byte(want)) {
+			t.Errorf("got:\n%s\nwant it to contain:\n%s", src, want)
+		}
+	}
+
+	compile(t, src)
+}
+
+func TestCompressedFS(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the go command")
+	}
+
+	gotool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+
+	files := [][2]string{
+		{"a.txt", strings.Repeat("hello, world\n", 100)},
+		{"dir/b.bin", "\x00\x01\x02\xff"},
+		{"empty.txt", ""},
+	}
+
+	src := render(t, Options{Compression: Compressors["gzip"], Level: 9, FS: "Files"}, files...)
+	if !bytes.Contains(src, []byte("\t\"a.txt\":     {a_txt, 1300},\n")) {
+		t.Fatalf("got:\n%s\nwant the files decompressed when opened", src)
+	}
+
+	// The files are checked by a test run with
+	// the generated package.
+
+	test := "package p\n\nimport (\n\t\"io\"\n\t\"testing\"\n\t\"testing/fstest\"\n)\n\nfunc TestFiles(t *testing.T) {\n"
+	test += "\tif err := fstest.TestFS(Files, \"a.txt\", \"dir/b.bin\", \"empty.txt\"); err != nil {\n\t\tt.Fatal(err)\n\t}\n"
+	for _, file := range files {
+		test += fmt.Sprintf(`
+	if f, err := Files.Open(%[1]q); err != nil {
+		t.Error(err)
+	} else if info, err := f.Stat(); err != nil || info.Size() != %[3]d {
+		t.Errorf("%%s: got size %%d, %%v, want %[3]d", %[1]q, info.Size(), err)
+	} else if data, err := io.ReadAll(f); err != nil || string(data) != %[2]q {
+		t.Errorf("%%s: got %%q, %%v", %[1]q, data, err)
+	}
+`, file[0], file[1], len(file[1]))
+	}
+
+	dir := t.TempDir()
+	for name, data := range map[string]string{"go.mod": "module p\n\ngo 1.16\n", "p.go": string(src), "p_test.go": test + "}\n"} {
+		if err = os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(gotool, "test", ".")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go test: %v\n%s", err, out)
+	}
+}
+
+func TestFSModTime(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(name, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	modTime := time.Unix(1600000000, 5)
+	if err := os.Chtimes(name, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+	out := NewOutput(Options{Package: "p", ModTime: true, FS: "F", HTTPFS: "H"})
+	if err = Embed(out, f, Input{Name: "a.txt", Ident: "a.txt"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = Finish(out); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := Render(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compile(t, src)
+	for _, want := range []string{
+		"var F_modTimes = map[string]time.Time{\n\t\"a.txt\": time.Unix(1600000000, 5),\n}\n",
+		"var H_httpModTimes = map[string]time.Time{\n\t\"a.txt\": time.Unix(1600000000, 5),\n}\n",
+	} {
+		if !bytes.Contains(src, []byte(want)) {
+			t.Errorf("got:\n%s\nwant it to contain:\n%s", src, want)
+		}
+	}
+}
+
+func TestFSPathCollision(t *testing.T) {
+	for _, opts := range []Options{{Package: "p", FS: "F"}, {Package: "p", HTTPFS: "H"}} {
+		out := NewOutput(opts)
+		for _, name := range []string{"../a.txt", "a.txt"} {
+			if err := Embed(out, strings.NewReader(name), Input{Name: name, Ident: name}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		want := "../a.txt and a.txt are both embedded as a.txt"
+		if err := Finish(out); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Finish: got %v, want %q", err, want)
+		}
+	}
+}
+
+func TestImports(t *testing.T) {
+	opts := Options{
+		Compression: Compressors["gzip"],
+		Level:       9,
+		Hashes:      []string{"sha256"},
+		Reader:      true,
+		BytesReader: true,
+		ModTime:     true,
+		Info:        true,
+		FS:          "Files",
+	}
+
+	for _, unformatted := range []bool{false, true} {
+		opts.Unformatted = unformatted
+		src := render(t, opts, [2]string{"a.txt", "data"}, [2]string{"b.txt", "more data"})
+		f, err := parser.ParseFile(token.NewFileSet(), "out.go", src, parser.ImportsOnly)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Every import is in a single block, right
+		// after the package clause, sorted and
+		// without duplicates.
+
+		if len(f.Decls) != 1 {
+			t.Fatalf("got %d import declarations, want 1", len(f.Decls))
+		}
+
+		var paths []string
+		for _, spec := range f.Imports {
+			paths = append(paths, spec.Path.Value)
+		}
+
+		for i := 1; i < len(paths); i++ {
+			if paths[i-1] >= paths[i] {
+				t.Errorf("imports not sorted and unique: %v", paths)
+				break
+			}
+		}
+
+		if !unformatted {
+			if formatted, err := format.Source(src); err != nil || !bytes.Equal(formatted, src) {
+				t.Errorf("formatting changed:\n%s\nto:\n%s", src, formatted)
+			}
+
+			compile(t, src)
+		}
+	}
+}
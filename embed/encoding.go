@@ -0,0 +1,52 @@
+package embed
+
+import (
+	"encoding/ascii85"
+	"encoding/base64"
+	"io"
+)
+
+// Encoding is a text encoding that data can be
+// embedded in as a string constant, which is
+// denser than a []byte literal.
+type Encoding struct {
+	Name       string
+	NewEncoder func(io.Writer) io.WriteCloser
+	Decode     string   // Format of the expression decoding a string, yielding the data and an error.
+	Decoder    string   // Format of the expression yielding a reader decoding a string, using strings.NewReader.
+	Imports    []string // Import paths used by the expressions, other than strings for Decoder.
+	Suffix     string   // Suffix of the identifier holding the encoded data.
+}
+
+// Encodings contains the encodings data can be
+// embedded in, by name.
+var Encodings = map[string]*Encoding{
+	"base64": {
+		"base64",
+		func(w io.Writer) io.WriteCloser { return base64.NewEncoder(base64.StdEncoding, w) },
+		"base64.StdEncoding.DecodeString(%s)",
+		"base64.NewDecoder(base64.StdEncoding, strings.NewReader(%s))",
+		[]string{"encoding/base64"},
+		"_b64",
+	},
+
+	// Ascii85 uses quotes and backslashes, which
+	// must be escaped.
+
+	"ascii85": {
+		"ascii85",
+		func(w io.Writer) io.WriteCloser { return ascii85.NewEncoder(&stringWriter{w: w}) },
+		"io.ReadAll(ascii85.NewDecoder(strings.NewReader(%s)))",
+		"ascii85.NewDecoder(strings.NewReader(%s))",
+		[]string{"encoding/ascii85", "io", "strings"},
+		"_a85",
+	},
+}
+
+// use imports the packages used by e's
+// expressions, except strings for Decoder.
+func (e *Encoding) use(dst *Output) {
+	for _, path := range e.Imports {
+		dst.Import(path)
+	}
+}
@@ -0,0 +1,80 @@
+package embed
+
+import (
+	"bufio"
+	"path/filepath"
+	"strings"
+)
+
+// textExts contains the extensions of the text
+// files whose line endings NormalizeEOL converts,
+// along with those given in TextExts.
+var textExts = map[string]bool{
+	".conf": true, ".css": true, ".csv": true, ".go": true,
+	".htm": true, ".html": true, ".ini": true, ".js": true,
+	".json": true, ".md": true, ".sh": true, ".sql": true,
+	".svg": true, ".tmpl": true, ".toml": true, ".tpl": true,
+	".tsv": true, ".txt": true, ".xml": true, ".yaml": true,
+	".yml": true,
+}
+
+// isText reports whether the named file is text,
+// according to its extension, which may also be
+// one of exts.
+func isText(name string, exts []string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	if textExts[ext] {
+		return true
+	}
+
+	for _, e := range exts {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if e == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(e, ".") {
+			e = "." + e
+		}
+
+		if e == ext {
+			return true
+		}
+	}
+
+	return false
+}
+
+// eolReader reads text, dropping the carriage
+// return from each CRLF line ending.
+type eolReader struct {
+	r *bufio.Reader
+}
+
+func (e eolReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		b, err := e.r.ReadByte()
+		if err != nil {
+			return n, err
+		}
+
+		if b == '\r' {
+			if next, err := e.r.Peek(1); err == nil && next[0] == '\n' {
+				continue
+			}
+		}
+
+		p[n] = b
+		n++
+
+		// Return what's been read rather than
+		// waiting for more.
+
+		if e.r.Buffered() == 0 {
+			break
+		}
+	}
+
+	return n, nil
+}
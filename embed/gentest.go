@@ -0,0 +1,81 @@
+package embed
+
+import (
+	"fmt"
+	"path"
+	"unicode"
+	"unicode/utf8"
+)
+
+// WriteTest returns a test file for out, which
+// checks that the contents embedded for each file
+// still match the embedded hashes, catching any
+// edits to the generated file. Each test is named
+// after the file's identifier and the hash, as in
+// TestLogo_pngSHA1.
+func WriteTest(out *Output) (*Output, error) {
+	test := &Output{opts: out.opts, test: true}
+	test.Import("testing")
+
+	for _, file := range out.Files {
+		data := "data := " + file.Value
+		if file.Fallible {
+			data = "data, err := " + file.Value + "\n\tif err != nil {\n\t\tt.Fatal(err)\n\t}\n"
+		}
+
+		if out.opts.CRC32 {
+			test.Import("hash/crc32")
+			_, err := fmt.Fprintf(test, `
+func %[4]s(t *testing.T) {
+	%[2]s
+	if got, want := crc32.ChecksumIEEE(data), uint32(%[1]s_CRC32); got != want {
+		t.Errorf("CRC-32 checksum of %%s is %%#08x, want %%#08x", %[3]q, got, want)
+	}
+}
+`, file.Ident, data, file.Path, testName(test, file, "CRC32"))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, alg := range out.opts.Hashes {
+			h := Hashes[alg]
+			test.Import("encoding/hex")
+			test.Import(h.Package)
+
+			want := file.Ident + "_" + h.Suffix
+			if out.opts.Array {
+				want += "[:]"
+			}
+
+			if out.opts.HashFormat != "hex" {
+				want = "hex.EncodeToString(" + want + ")"
+			}
+
+			_, err := fmt.Fprintf(test, `
+func %[7]s(t *testing.T) {
+	%[3]s
+	h := %[4]s.New()
+	h.Write(data)
+	if got, want := hex.EncodeToString(h.Sum(nil)), %[5]s; got != want {
+		t.Errorf("%[2]s hash of %%s is %%s, want %%s", %[6]q, got, want)
+	}
+}
+`, file.Ident, h.Suffix, data, path.Base(h.Package), want, file.Path, testName(test, file, h.Suffix))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return test, nil
+}
+
+// testName returns a unique name in test for the
+// test of file's hash with the given suffix. The
+// identifier is capitalised, as the go command
+// ignores tests named Test and a lowercase letter.
+func testName(test *Output, file File, suffix string) string {
+	r, n := utf8.DecodeRuneInString(file.Ident)
+	return test.Ident("Test" + string(unicode.ToUpper(r)) + file.Ident[n:] + suffix)
+}
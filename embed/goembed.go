@@ -0,0 +1,45 @@
+package embed
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// goEmbedName returns the name of the embed.FS
+// holding the files embedded from dir with
+// GoEmbed, such as StaticFS for static.
+func goEmbedName(dir string) string {
+	return exported(sanitise(path.Base(dir))) + "FS"
+}
+
+// embedPattern returns name as a go:embed
+// pattern, quoted if it contains spaces or
+// quotes.
+func embedPattern(name string) string {
+	if strings.ContainsAny(name, " \t\"`") {
+		return strconv.Quote(name)
+	}
+
+	return name
+}
+
+// WriteGoEmbedFS writes the embed.FS with the
+// given name holding every file embedded in out
+// with GoEmbed, under the paths they were copied
+// to.
+func WriteGoEmbedFS(out *Output, name string) error {
+	if len(out.Files) == 0 {
+		return nil
+	}
+
+	out.Import("embed")
+	patterns := make([]string, len(out.Files))
+	for i, file := range out.Files {
+		patterns[i] = embedPattern(file.EmbedPath)
+	}
+
+	_, err := fmt.Fprintf(out, "\n// %s holds the embedded files, under %s.\n//\n//go:embed %s\nvar %[1]s embed.FS\n", name, out.opts.GoEmbed, strings.Join(patterns, " "))
+	return err
+}
@@ -0,0 +1,25 @@
+package embed
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+)
+
+// Hash is a hash algorithm that can be embedded.
+type Hash struct {
+	New     func() hash.Hash
+	Suffix  string // Suffix of the embedded identifier.
+	Package string // Import path of the implementation.
+}
+
+// Hashes contains the algorithms whose hashes
+// can be embedded, by name.
+var Hashes = map[string]Hash{
+	"md5":    {md5.New, "MD5", "crypto/md5"},
+	"sha1":   {sha1.New, "SHA1", "crypto/sha1"},
+	"sha256": {sha256.New, "SHA256", "crypto/sha256"},
+	"sha512": {sha512.New, "SHA512", "crypto/sha512"},
+}
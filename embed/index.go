@@ -0,0 +1,807 @@
+package embed
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// WriteMap writes a map with the given name from
+// the path of each file embedded in out to its
+// contents. When the contents can only be
+// obtained by decompression, the map is populated
+// by init.
+func WriteMap(out *Output, name string) error {
+	return writeIndex(out, name, "map[string][]byte", func(file File) string {
+		return file.Path
+	})
+}
+
+// WriteHashedNames writes maps from the path of
+// each file in the map with the given name to one
+// including a hash of its contents, and back, so
+// that files can be served from paths that change
+// whenever their contents do, and cached forever.
+func WriteHashedNames(out *Output, name string) error {
+	_, err := fmt.Fprintf(out, "\n// %s_Hashed maps the path of each file in %[1]s\n// to one including a hash of its contents.\nvar %[1]s_Hashed = map[string]string{\n", name)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range out.Files {
+		if _, err = fmt.Fprintf(out, "\t%q: %q,\n", file.Path, hashedPath(file)); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(out, "}\n\n// %s_Original maps each path in %[1]s_Hashed\n// back to the file's path in %[1]s.\nvar %[1]s_Original = map[string]string{\n", name)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range out.Files {
+		if _, err = fmt.Fprintf(out, "\t%q: %q,\n", hashedPath(file), file.Path); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(out, "}\n")
+	return err
+}
+
+// hashedPath returns the path of file with the
+// start of its hash inserted before the extension.
+func hashedPath(file File) string {
+	ext := filepath.Ext(file.Path)
+	sum := hex.EncodeToString(file.Sum)
+	if len(sum) > 8 {
+		sum = sum[:8]
+	}
+
+	return strings.TrimSuffix(file.Path, ext) + "." + sum + ext
+}
+
+// WriteAssets writes the Asset and AssetNames
+// functions, as written by go-bindata, which look
+// up files in the map with the given name.
+func WriteAssets(out *Output, name string) error {
+	out.Import("fmt")
+	out.Import("sort")
+
+	_, err := fmt.Fprintf(out, assetsTemplate, name)
+	return err
+}
+
+const assetsTemplate = `
+// Asset returns the contents of the embedded file
+// with the given path.
+func Asset(name string) ([]byte, error) {
+	if data, ok := %[1]s[name]; ok {
+		return data, nil
+	}
+
+	return nil, fmt.Errorf("asset not found: %%q", name)
+}
+
+// AssetNames returns the sorted paths of the
+// embedded files.
+func AssetNames() []string {
+	names := make([]string, 0, len(%[1]s))
+	for name := range %[1]s {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+`
+
+// writeIndex writes a variable with the given name
+// and map type from the key of each file embedded
+// in out to its contents.
+func writeIndex(out *Output, name, typ string, key func(File) string) error {
+	var fallible bool
+	for _, file := range out.Files {
+		if file.Fallible {
+			fallible = true
+			break
+		}
+	}
+
+	if fallible {
+		_, err := fmt.Fprintf(out, "\nvar %s = make(%s, %d)\n\nfunc init() {\n\tvar err error\n", name, typ, len(out.Files))
+		if err != nil {
+			return err
+		}
+
+		for _, file := range out.Files {
+			_, err = fmt.Fprintf(out, "\tif %s[%q], err = %s; err != nil {\n\t\tpanic(err)\n\t}\n", name, key(file), file.Value)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = fmt.Fprintf(out, "}\n")
+		return err
+	}
+
+	_, err := fmt.Fprintf(out, "\nvar %s = %s{\n", name, typ)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range out.Files {
+		if _, err = fmt.Fprintf(out, "\t%q: %s,\n", key(file), file.Value); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(out, "}\n")
+	return err
+}
+
+// WriteRegister writes an init function passing
+// the path and contents of each file embedded in
+// out to the function with the given name, which
+// is not generated and must be provided by the
+// package. This lets files written separately
+// populate a single registry.
+func WriteRegister(out *Output, name string) error {
+	if len(out.Files) == 0 {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(out, "\nfunc init() {\n")
+	if err != nil {
+		return err
+	}
+
+	for _, file := range out.Files {
+		if file.Fallible {
+			_, err = fmt.Fprintf(out, "\tif data, err := %s; err != nil {\n\t\tpanic(err)\n\t} else {\n\t\t%s(%q, data)\n\t}\n", file.Value, name, file.Path)
+		} else {
+			_, err = fmt.Fprintf(out, "\t%s(%q, %s)\n", name, file.Path, file.Value)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(out, "}\n")
+	return err
+}
+
+// WriteStruct writes a variable with the given
+// name, holding each file embedded in out in an
+// exported field named after it. Fields that
+// collide are numbered, like identifiers. When
+// the contents can only be obtained by
+// decompression, the fields are set by init.
+func WriteStruct(out *Output, name string) error {
+	var names Output
+	fields := make([]string, len(out.Files))
+	var fallible bool
+	for i, file := range out.Files {
+		fields[i] = names.Ident(exported(file.Ident))
+		if file.Fallible {
+			fallible = true
+		}
+	}
+
+	decl := "\nvar " + name + " = struct {\n"
+	if fallible {
+		decl = "\nvar " + name + " struct {\n"
+	}
+
+	for _, field := range fields {
+		decl += "\t" + field + " []byte\n"
+	}
+
+	if fallible {
+		decl += "}\n\nfunc init() {\n\tvar err error\n"
+		for i, file := range out.Files {
+			decl += fmt.Sprintf("\tif %s.%s, err = %s; err != nil {\n\t\tpanic(err)\n\t}\n", name, fields[i], file.Value)
+		}
+	} else {
+		decl += "}{\n"
+		for i, file := range out.Files {
+			decl += fmt.Sprintf("\t%s: %s,\n", fields[i], file.Value)
+		}
+	}
+
+	_, err := fmt.Fprintf(out, "%s}\n", decl)
+	return err
+}
+
+// structDir is a directory of the struct written
+// by WriteNestedStruct, whose fields are either
+// files or subdirectories.
+type structDir struct {
+	names  Output
+	fields []structField
+	dirs   map[string]*structDir // By path element.
+}
+
+type structField struct {
+	name string
+	file File
+	dir  *structDir
+}
+
+// add adds file to d, at the given path elements.
+func (d *structDir) add(elems []string, file File) {
+	if len(elems) == 1 {
+		d.fields = append(d.fields, structField{name: d.names.Ident(exported(sanitise(elems[0]))), file: file})
+		return
+	}
+
+	sub, ok := d.dirs[elems[0]]
+	if !ok {
+		if d.dirs == nil {
+			d.dirs = make(map[string]*structDir)
+		}
+
+		sub = new(structDir)
+		d.dirs[elems[0]] = sub
+		d.fields = append(d.fields, structField{name: d.names.Ident(exported(sanitise(elems[0]))), dir: sub})
+	}
+
+	sub.add(elems[1:], file)
+}
+
+// writeType writes the struct type of d.
+func (d *structDir) writeType(b *strings.Builder) {
+	b.WriteString("struct {\n")
+	for _, field := range d.fields {
+		b.WriteString(field.name + " ")
+		if field.dir != nil {
+			field.dir.writeType(b)
+		} else {
+			b.WriteString("[]byte\n")
+		}
+	}
+
+	b.WriteString("}\n")
+}
+
+// writeInit writes the statements setting the
+// files in d, whose fields are selected by expr.
+func (d *structDir) writeInit(b *strings.Builder, expr string) {
+	for _, field := range d.fields {
+		switch {
+		case field.dir != nil:
+			field.dir.writeInit(b, expr+"."+field.name)
+		case field.file.Fallible:
+			fmt.Fprintf(b, "\tif %s.%s, err = %s; err != nil {\n\t\tpanic(err)\n\t}\n", expr, field.name, field.file.Value)
+		default:
+			fmt.Fprintf(b, "\t%s.%s = %s\n", expr, field.name, field.file.Value)
+		}
+	}
+}
+
+// WriteNestedStruct writes a struct like
+// WriteStruct, but with Nested, where each
+// directory is a struct holding its files and
+// subdirectories, mirroring the file tree. The
+// fields are set by init.
+func WriteNestedStruct(out *Output, name string) error {
+	var root structDir
+	var fallible bool
+	for _, file := range out.Files {
+		elems := strings.Split(fsPath(file), "/")
+		if out.opts.ASCIINames {
+			for i := range elems {
+				elems[i] = ascii(elems[i])
+			}
+		}
+
+		root.add(elems, file)
+		if file.Fallible {
+			fallible = true
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("\nvar " + name + " ")
+	root.writeType(&b)
+	b.WriteString("\nfunc init() {\n")
+	if fallible {
+		b.WriteString("\tvar err error\n")
+	}
+
+	root.writeInit(&b, name)
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+// WriteFS writes a file system with the given name
+// holding each file embedded in out, along with
+// the types implementing it.
+func WriteFS(out *Output, name string) error {
+	if err := checkPaths(out); err != nil {
+		return err
+	}
+
+	out.Import("bytes")
+	out.Import("io")
+	out.Import("io/fs")
+	out.Import("path")
+	out.Import("sort")
+	out.Import("strings")
+	out.Import("time")
+
+	_, err := fmt.Fprintf(out, "\n// %s is a file system holding the embedded files.", name)
+	if err != nil {
+		return err
+	}
+
+	// Compressed files are only decompressed when
+	// they're first read, rather than all at once
+	// when the program starts.
+
+	var fallible bool
+	for _, file := range out.Files {
+		fallible = fallible || file.Fallible
+	}
+
+	if fallible {
+		err = writeEntries(out, name)
+	} else {
+		err = writeIndex(out, name, name+"_FS", fsPath)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err = writeModes(out, name+"_modes", "fs.FileMode"); err != nil {
+		return err
+	}
+
+	if err = writeModTimes(out, name+"_modTimes"); err != nil {
+		return err
+	}
+
+	if fallible {
+		_, err = fmt.Fprintf(out, fsEntryTemplate+fsTemplate, name, "e", "e.size")
+	} else {
+		_, err = fmt.Fprintf(out, fsBytesTemplate+fsTemplate, name, "data", "int64(len(data))")
+	}
+
+	return err
+}
+
+// writeEntries writes the file system with the
+// given name holding each file embedded in out as
+// an entry giving its size, and the function that
+// returns its contents.
+func writeEntries(out *Output, name string) error {
+	_, err := fmt.Fprintf(out, "\nvar %s = %[1]s_FS{\n", name)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range out.Files {
+		data := strings.TrimSuffix(file.Value, "()")
+		if !file.Fallible || data == file.Value {
+			data = fmt.Sprintf("func() ([]byte, error) { return %s, nil }", file.Value)
+		}
+
+		if _, err = fmt.Fprintf(out, "\t%q: {%s, %d},\n", fsPath(file), data, file.Size); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(out, "}\n")
+	return err
+}
+
+// WriteHTTPFS writes an http.FileSystem with the
+// given name serving each file embedded in out,
+// along with the types implementing it.
+func WriteHTTPFS(out *Output, name string) error {
+	if err := checkPaths(out); err != nil {
+		return err
+	}
+
+	out.Import("bytes")
+	out.Import("net/http")
+	out.Import("os")
+	out.Import("path")
+	out.Import("strings")
+	out.Import("time")
+
+	_, err := fmt.Fprintf(out, "\n// %s is an http.FileSystem serving the embedded files.", name)
+	if err != nil {
+		return err
+	}
+
+	if err = writeIndex(out, name, name+"_HTTPFS", fsPath); err != nil {
+		return err
+	}
+
+	if err = writeModes(out, name+"_httpModes", "os.FileMode"); err != nil {
+		return err
+	}
+
+	if err = writeModTimes(out, name+"_httpModTimes"); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(out, httpFSTemplate, name)
+	return err
+}
+
+// writeModes writes a map with the given name
+// from the path of each file embedded in out to
+// its permission bits, where they're known.
+// Files without them are given read permission.
+func writeModes(out *Output, name, typ string) error {
+	_, err := fmt.Fprintf(out, "\nvar %s = map[string]%s{\n", name, typ)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range out.Files {
+		if file.Mode == 0 {
+			continue
+		}
+
+		if _, err = fmt.Fprintf(out, "\t%q: %#o,\n", fsPath(file), file.Mode); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(out, "}\n")
+	return err
+}
+
+// writeModTimes writes a map with the given name
+// from the path of each file embedded in out to
+// its modification time, where it's known and
+// embedded. Files without one have the zero time.
+func writeModTimes(out *Output, name string) error {
+	_, err := fmt.Fprintf(out, "\nvar %s = map[string]time.Time{\n", name)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range out.Files {
+		if file.ModTime.IsZero() {
+			continue
+		}
+
+		if _, err = fmt.Fprintf(out, "\t%q: %s,\n", fsPath(file), timeExpr(file.ModTime)); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(out, "}\n")
+	return err
+}
+
+// fsPath returns the path of file in a file
+// system, which must be unrooted, slash-separated
+// and free of . and .. elements.
+func fsPath(file File) string {
+	name := path.Clean(filepath.ToSlash(file.Path))
+	for {
+		switch {
+		case strings.HasPrefix(name, "/"):
+			name = name[1:]
+		case strings.HasPrefix(name, "../"):
+			name = name[3:]
+		default:
+			return name
+		}
+	}
+}
+
+// checkPaths returns an error if any two files
+// embedded in out have the same path in a file
+// system, as fsPath can give for different paths.
+func checkPaths(out *Output) error {
+	paths := make(map[string]string)
+	for _, file := range out.Files {
+		name := fsPath(file)
+		if other, ok := paths[name]; ok {
+			return fmt.Errorf("%s and %s are both embedded as %s", other, file.Path, name)
+		}
+
+		paths[name] = file.Path
+	}
+
+	return nil
+}
+
+// fsBytesTemplate starts the file system written
+// by WriteFS, for files held as []byte.
+const fsBytesTemplate = `
+// %[1]s_FS is a read-only file system holding
+// files by path. It implements fs.FS, fs.ReadFileFS
+// and fs.ReadDirFS.
+type %[1]s_FS map[string][]byte
+
+// Open opens the named file or directory.
+func (f %[1]s_FS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if data, ok := f[name]; ok {
+		info := %[1]s_info{name: path.Base(name), size: int64(len(data)), mode: %[1]s_modes[name], modTime: %[1]s_modTimes[name]}
+		return &%[1]s_file{Reader: bytes.NewReader(data), info: info}, nil
+	}
+
+	entries, err := f.ReadDir(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	info := %[1]s_info{name: path.Base(name), dir: true}
+	return &%[1]s_dir{info: info, entries: entries}, nil
+}
+
+// ReadFile returns a copy of the named file's contents.
+func (f %[1]s_FS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+
+	data, ok := f[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return append([]byte(nil), data...), nil
+}
+`
+
+// fsEntryTemplate starts the file system written
+// by WriteFS instead, for files that can only be
+// read once decompressed.
+const fsEntryTemplate = `
+// %[1]s_FS is a read-only file system holding
+// files by path. It implements fs.FS, fs.ReadFileFS
+// and fs.ReadDirFS.
+type %[1]s_FS map[string]%[1]s_entry
+
+// %[1]s_entry is a file in a %[1]s_FS, whose
+// contents are decompressed when it's first read.
+type %[1]s_entry struct {
+	data func() ([]byte, error)
+	size int64
+}
+
+// Open opens the named file or directory.
+func (f %[1]s_FS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if e, ok := f[name]; ok {
+		data, err := e.data()
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+
+		info := %[1]s_info{name: path.Base(name), size: e.size, mode: %[1]s_modes[name], modTime: %[1]s_modTimes[name]}
+		return &%[1]s_file{Reader: bytes.NewReader(data), info: info}, nil
+	}
+
+	entries, err := f.ReadDir(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	info := %[1]s_info{name: path.Base(name), dir: true}
+	return &%[1]s_dir{info: info, entries: entries}, nil
+}
+
+// ReadFile returns a copy of the named file's contents.
+func (f %[1]s_FS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+
+	e, ok := f[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+
+	data, err := e.data()
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+
+	return append([]byte(nil), data...), nil
+}
+`
+
+// fsTemplate holds the rest of the file system,
+// given how to range over its files and the size
+// of each.
+const fsTemplate = `
+// ReadDir returns the entries of the named directory,
+// sorted by name.
+func (f %[1]s_FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	prefix := name + "/"
+	if name == "." {
+		prefix = ""
+	}
+
+	var entries []fs.DirEntry
+	dirs := make(map[string]bool)
+	for file, %[2]s := range f {
+		if !strings.HasPrefix(file, prefix) {
+			continue
+		}
+
+		rest := file[len(prefix):]
+		if i := strings.IndexByte(rest, '/'); i >= 0 {
+			if !dirs[rest[:i]] {
+				dirs[rest[:i]] = true
+				entries = append(entries, %[1]s_dirEntry{%[1]s_info{name: rest[:i], dir: true}})
+			}
+
+			continue
+		}
+
+		entries = append(entries, %[1]s_dirEntry{%[1]s_info{name: rest, size: %[3]s, mode: %[1]s_modes[file], modTime: %[1]s_modTimes[file]}})
+	}
+
+	if len(entries) == 0 && name != "." {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	return entries, nil
+}
+
+type %[1]s_file struct {
+	*bytes.Reader
+	info %[1]s_info
+}
+
+func (f *%[1]s_file) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *%[1]s_file) Close() error               { return nil }
+
+type %[1]s_dir struct {
+	info    %[1]s_info
+	entries []fs.DirEntry
+}
+
+func (d *%[1]s_dir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *%[1]s_dir) Close() error               { return nil }
+
+func (d *%[1]s_dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+func (d *%[1]s_dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	entries := d.entries
+	if n <= 0 {
+		d.entries = nil
+		return entries, nil
+	}
+
+	if len(entries) == 0 {
+		return nil, io.EOF
+	}
+
+	if n > len(entries) {
+		n = len(entries)
+	}
+
+	d.entries = entries[n:]
+	return entries[:n], nil
+}
+
+type %[1]s_info struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+	dir     bool
+}
+
+func (i %[1]s_info) Name() string       { return i.name }
+func (i %[1]s_info) Size() int64        { return i.size }
+func (i %[1]s_info) ModTime() time.Time { return i.modTime }
+func (i %[1]s_info) IsDir() bool        { return i.dir }
+func (i %[1]s_info) Sys() interface{}   { return nil }
+
+func (i %[1]s_info) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0555
+	}
+
+	if i.mode != 0 {
+		return i.mode
+	}
+
+	return 0444
+}
+
+type %[1]s_dirEntry struct {
+	info %[1]s_info
+}
+
+func (e %[1]s_dirEntry) Name() string               { return e.info.name }
+func (e %[1]s_dirEntry) IsDir() bool                { return e.info.dir }
+func (e %[1]s_dirEntry) Type() fs.FileMode          { return e.info.Mode().Type() }
+func (e %[1]s_dirEntry) Info() (fs.FileInfo, error) { return e.info, nil }
+`
+
+const httpFSTemplate = `
+// %[1]s_HTTPFS is an http.FileSystem serving files
+// by path. Directories exist implicitly and are
+// listed as empty.
+type %[1]s_HTTPFS map[string][]byte
+
+// Open opens the named file or directory.
+func (f %[1]s_HTTPFS) Open(name string) (http.File, error) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if data, ok := f[name]; ok {
+		info := %[1]s_httpInfo{name: path.Base(name), size: int64(len(data)), mode: %[1]s_httpModes[name], modTime: %[1]s_httpModTimes[name]}
+		return &%[1]s_httpFile{Reader: bytes.NewReader(data), info: info}, nil
+	}
+
+	for file := range f {
+		if name == "" || strings.HasPrefix(file, name+"/") {
+			info := %[1]s_httpInfo{name: path.Base("/" + name), dir: true}
+			return &%[1]s_httpFile{Reader: bytes.NewReader(nil), info: info}, nil
+		}
+	}
+
+	return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+}
+
+type %[1]s_httpFile struct {
+	*bytes.Reader
+	info %[1]s_httpInfo
+}
+
+func (f *%[1]s_httpFile) Stat() (os.FileInfo, error)            { return f.info, nil }
+func (f *%[1]s_httpFile) Readdir(int) ([]os.FileInfo, error) { return nil, nil }
+func (f *%[1]s_httpFile) Close() error                        { return nil }
+
+type %[1]s_httpInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+	dir     bool
+}
+
+func (i %[1]s_httpInfo) Name() string       { return i.name }
+func (i %[1]s_httpInfo) Size() int64        { return i.size }
+func (i %[1]s_httpInfo) ModTime() time.Time { return i.modTime }
+func (i %[1]s_httpInfo) IsDir() bool        { return i.dir }
+func (i %[1]s_httpInfo) Sys() interface{}   { return nil }
+
+func (i %[1]s_httpInfo) Mode() os.FileMode {
+	if i.dir {
+		return os.ModeDir | 0555
+	}
+
+	if i.mode != 0 {
+		return i.mode
+	}
+
+	return 0444
+}
+`
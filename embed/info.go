@@ -0,0 +1,65 @@
+package embed
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// infoType is the type written with Info.
+const infoType = "FileInfo"
+
+// writeInfo writes a variable holding the metadata
+// of the named file, with the fields enabled by
+// Size, ModTime, Mode and MIME as well as its
+// name, in place of the separate declarations.
+func writeInfo(dst *Output, name, ident string, size int64, modTime time.Time, mode os.FileMode, contentType string) error {
+	opts := &dst.opts
+	fields := fmt.Sprintf("Name: %q", name)
+	if opts.Size {
+		fields += fmt.Sprintf(", Size: %d", size)
+	}
+
+	if opts.ModTime {
+		dst.Import("time")
+		fields += ", ModTime: " + timeExpr(modTime)
+	}
+
+	if opts.Mode {
+		fields += fmt.Sprintf(", Mode: %#o", mode)
+	}
+
+	if opts.MIME {
+		fields += fmt.Sprintf(", ContentType: %q", contentType)
+	}
+
+	_, err := fmt.Fprintf(dst, "\n// Metadata of %s\nvar %s_Info = %s{%s}\n", name, ident, infoType, fields)
+	return err
+}
+
+// WriteInfoType writes the type of the variables
+// written by writeInfo, with the same fields.
+func WriteInfoType(out *Output) error {
+	opts := &out.opts
+	fields := "\tName string // Path of the file.\n"
+	if opts.Size {
+		fields += "\tSize int64 // Size in bytes, before any compression.\n"
+	}
+
+	if opts.ModTime {
+		out.Import("time")
+		fields += "\tModTime time.Time // Modification time.\n"
+	}
+
+	if opts.Mode {
+		out.Import("os")
+		fields += "\tMode os.FileMode // Permission bits.\n"
+	}
+
+	if opts.MIME {
+		fields += "\tContentType string // Content type.\n"
+	}
+
+	_, err := fmt.Fprintf(out, "\n// %s describes an embedded file.\ntype %[1]s struct {\n%s}\n", infoType, fields)
+	return err
+}
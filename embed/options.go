@@ -0,0 +1,303 @@
+package embed
+
+import (
+	"fmt"
+	"go/build/constraint"
+)
+
+// DefaultMarker is the comment marking generated
+// files, unless Options gives another.
+const DefaultMarker = "// Code generated by embed; DO NOT EDIT."
+
+// Options controls the code generated for an
+// Output. The embed command sets them from its
+// flags, which are named after the fields and
+// document them, and checks that they're
+// consistent. Conflicting options, such as
+// String with Encoding, give undefined results.
+type Options struct {
+	Package string // Name of the generated package.
+
+	// Compression is the format data is compressed
+	// in, from Compressors, or nil if it isn't.
+	// Level is the compression level, as used by
+	// compress/flate, which is also used by Bundle.
+
+	Compression     *Compressor
+	Level           int
+	SmartCompress   bool  // Whether to store data that compression wouldn't shrink uncompressed.
+	MinCompressSize int64 // Size below which data is stored uncompressed.
+
+	// Hashes contains the names of the algorithms
+	// in Hashes whose hashes are embedded, in that
+	// order. HashFormat is "bytes" or "hex", which
+	// is used for any other value.
+
+	Hashes     []string
+	HashFirst  bool // Whether to write hashes before the data.
+	CRC32      bool
+	HashFormat string
+
+	// The form the data is embedded in, as a []byte
+	// literal by default. Encoding is from Encodings.
+
+	String      bool
+	Encoding    *Encoding
+	Raw         bool
+	Lines       bool
+	Const       bool // Whether to declare strings as constants.
+	Array       bool
+	Chunk       int // Size above which []byte literals are split, or 0.
+	ChunkString int // Size above which string literals are split, or 0.
+	Width       int // Bytes per line in []byte literals, or 0 for DefaultWidth.
+	Pretty      int // Lines in each block of []byte literals, after its offset, or 0.
+	Blob        bool
+	Bundle      bool
+
+	// GoEmbed is the directory, relative to the
+	// output, that the caller copies each File's
+	// Data to, at its EmbedPath. The files are then
+	// embedded with go:embed rather than as
+	// literals, along with an embed.FS named after
+	// the directory, such as StaticFS for static.
+
+	GoEmbed string
+
+	// Identifiers are derived from each Input's
+	// Ident, with TrimPrefix removed and Prefix
+	// added.
+
+	Prefix     string
+	TrimPrefix string
+	Export     bool
+	ASCIINames bool // Whether to transliterate non-ASCII letters.
+
+	// Declarations written for each file.
+
+	Reader         bool
+	BytesReader    bool // Whether to return a *bytes.Reader, which can seek, as well.
+	CopyAccessor   bool // Whether []byte data is only returned as a copy, by a function.
+	Funcs          bool // Whether []byte data is declared as functions, so the linker drops those never called.
+	ModTime        bool
+	Mode           bool
+	Size           bool
+	MIME           bool
+	Info           bool // Whether to collect the above metadata in a FileInfo.
+	Docs           bool
+	NoComments     bool
+	LineDirectives bool // Whether to attribute the declarations to the original file with a //line directive.
+
+	// NormalizeEOL converts CRLF line endings to LF
+	// in text files: those with extensions in
+	// TextExts, or any of the common text formats.
+
+	NormalizeEOL bool
+	TextExts     []string
+
+	// Text files, as for NormalizeEOL, are
+	// converted from TranscodeFrom to TranscodeTo,
+	// from Charsets, before they're embedded, if
+	// both are given.
+
+	TranscodeFrom *Charset
+	TranscodeTo   *Charset
+
+	// Declarations written by Finish, for all of
+	// the files. Map must be given for Assets and
+	// HashedNames, and Struct for Nested.
+
+	Map         string
+	Assets      bool
+	HashedNames bool
+	Register    string
+	Struct      string
+	Nested      bool
+	FS          string
+	HTTPFS      string
+
+	// Comments and directives written at the top
+	// of the file. Header must already be written
+	// as comments, and Marker defaults to
+	// DefaultMarker. Generate, if given, is written
+	// after the package clause, except in tests.
+
+	Header      string
+	Marker      string
+	Tags        constraint.Expr
+	Generate    string
+	Unformatted bool // Whether to skip formatting with gofmt.
+
+	// Progress, if given, is called with the number
+	// of bytes read from each file as Embed reads
+	// them, which may be concurrently.
+
+	Progress func(name string, n int)
+
+	// Cache, if given, holds the declarations that
+	// Embed wrote for files in earlier runs, to be
+	// copied rather than embedding them again. It
+	// must have been created for these options.
+
+	Cache *Cache
+}
+
+// Input names data to be embedded.
+type Input struct {
+	Name  string // Name of the file in generated code.
+	Ident string // Name from which identifiers are derived.
+	Var   string // Identifier to use as is, if given.
+}
+
+// NewOutput returns an empty Output using the
+// given options, with the identifiers they
+// need reserved.
+func NewOutput(opts Options) *Output {
+	if opts.Width < 1 {
+		opts.Width = DefaultWidth
+	}
+
+	if opts.Marker == "" {
+		opts.Marker = DefaultMarker
+	}
+
+	for _, name := range []*string{&opts.Map, &opts.Struct, &opts.FS, &opts.HTTPFS} {
+		if *name != "" {
+			*name = sanitise(*name)
+		}
+	}
+
+	out := &Output{opts: opts}
+	if opts.Map != "" {
+		if opts.HashedNames {
+			out.Ident(opts.Map, "_Hashed", "_Original")
+		} else {
+			out.Ident(opts.Map)
+		}
+	}
+
+	if opts.Assets {
+		out.Ident("Asset")
+		out.Ident("AssetNames")
+	}
+
+	if opts.Register != "" {
+		out.Ident(opts.Register)
+	}
+
+	if opts.Struct != "" {
+		out.Ident(opts.Struct)
+	}
+
+	if opts.FS != "" {
+		out.Ident(opts.FS, "_FS", "_entry", "_modes", "_modTimes", "_file", "_dir", "_info", "_dirEntry")
+	}
+
+	if opts.HTTPFS != "" {
+		out.Ident(opts.HTTPFS, "_HTTPFS", "_httpModes", "_httpModTimes", "_httpFile", "_httpInfo")
+	}
+
+	if opts.GoEmbed != "" {
+		out.Ident(goEmbedName(opts.GoEmbed))
+	}
+
+	if opts.Blob {
+		out.Ident(blobName)
+		out.Ident(blobIndex)
+		out.Ident("Get")
+	}
+
+	if opts.Info {
+		out.Ident(infoType)
+	}
+
+	if opts.Bundle {
+		out.Ident(bundleName)
+		out.Ident(bundleState)
+		out.Ident(bundleFile)
+		out.Ident("extract" + bundleName)
+		out.Ident("AssetNames")
+	}
+
+	return out
+}
+
+// Finish writes the declarations covering every
+// file embedded in out, as given by its options,
+// once they have all been embedded.
+func Finish(out *Output) error {
+	opts := &out.opts
+	if opts.GoEmbed != "" {
+		if err := WriteGoEmbedFS(out, goEmbedName(opts.GoEmbed)); err != nil {
+			return fmt.Errorf("failed to write embed.FS: %v", err)
+		}
+	}
+
+	if opts.Blob {
+		if err := WriteBlob(out); err != nil {
+			return fmt.Errorf("failed to write blob: %v", err)
+		}
+	}
+
+	if opts.Info {
+		if err := WriteInfoType(out); err != nil {
+			return fmt.Errorf("failed to write info type: %v", err)
+		}
+	}
+
+	if opts.Bundle {
+		if err := WriteBundle(out); err != nil {
+			return fmt.Errorf("failed to write bundle: %v", err)
+		}
+	}
+
+	if opts.Map != "" {
+		if err := WriteMap(out, opts.Map); err != nil {
+			return fmt.Errorf("failed to write map: %v", err)
+		}
+	}
+
+	if opts.HashedNames {
+		if err := WriteHashedNames(out, opts.Map); err != nil {
+			return fmt.Errorf("failed to write hashed names: %v", err)
+		}
+	}
+
+	if opts.Assets {
+		if err := WriteAssets(out, opts.Map); err != nil {
+			return fmt.Errorf("failed to write assets: %v", err)
+		}
+	}
+
+	if opts.Register != "" {
+		if err := WriteRegister(out, opts.Register); err != nil {
+			return fmt.Errorf("failed to write registration: %v", err)
+		}
+	}
+
+	if opts.Struct != "" {
+		var err error
+		if opts.Nested {
+			err = WriteNestedStruct(out, opts.Struct)
+		} else {
+			err = WriteStruct(out, opts.Struct)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to write struct: %v", err)
+		}
+	}
+
+	if opts.FS != "" {
+		if err := WriteFS(out, opts.FS); err != nil {
+			return fmt.Errorf("failed to write file system: %v", err)
+		}
+	}
+
+	if opts.HTTPFS != "" {
+		if err := WriteHTTPFS(out, opts.HTTPFS); err != nil {
+			return fmt.Errorf("failed to write file system: %v", err)
+		}
+	}
+
+	return nil
+}
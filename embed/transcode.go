@@ -0,0 +1,133 @@
+package embed
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Charset is a text encoding that data can be
+// transcoded from or to before it's embedded.
+type Charset struct {
+	Name   string
+	Decode func(data []byte) ([]rune, error)
+	Encode func(text []rune) ([]byte, error)
+}
+
+// Charsets contains the encodings data can be
+// transcoded between, by name.
+var Charsets = map[string]*Charset{
+	"utf-8":      {"utf-8", decodeUTF8, encodeUTF8},
+	"utf-16":     {"utf-16", decodeUTF16BOM, encodeUTF16BOM},
+	"utf-16le":   {"utf-16le", decodeUTF16(binary.LittleEndian), encodeUTF16(binary.LittleEndian)},
+	"utf-16be":   {"utf-16be", decodeUTF16(binary.BigEndian), encodeUTF16(binary.BigEndian)},
+	"iso-8859-1": latin1,
+	"latin1":     latin1,
+}
+
+var latin1 = &Charset{"iso-8859-1", decodeLatin1, encodeLatin1}
+
+// transcode converts data from one encoding to
+// another. Any byte order mark is dropped, so it's
+// only written if to's encoding adds one.
+func transcode(data []byte, from, to *Charset) ([]byte, error) {
+	text, err := from.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %v", from.Name, err)
+	}
+
+	if len(text) > 0 && text[0] == '\ufeff' {
+		text = text[1:]
+	}
+
+	data, err = to.Encode(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %v", to.Name, err)
+	}
+
+	return data, nil
+}
+
+func decodeUTF8(data []byte) ([]rune, error) {
+	if !utf8.Valid(data) {
+		return nil, errors.New("invalid UTF-8")
+	}
+
+	return []rune(string(data)), nil
+}
+
+func encodeUTF8(text []rune) ([]byte, error) {
+	return []byte(string(text)), nil
+}
+
+func decodeUTF16(order binary.ByteOrder) func([]byte) ([]rune, error) {
+	return func(data []byte) ([]rune, error) {
+		if len(data)%2 != 0 {
+			return nil, errors.New("odd number of bytes")
+		}
+
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = order.Uint16(data[2*i:])
+		}
+
+		return utf16.Decode(units), nil
+	}
+}
+
+func encodeUTF16(order binary.ByteOrder) func([]rune) ([]byte, error) {
+	return func(text []rune) ([]byte, error) {
+		units := utf16.Encode(text)
+		data := make([]byte, 2*len(units))
+		for i, u := range units {
+			order.PutUint16(data[2*i:], u)
+		}
+
+		return data, nil
+	}
+}
+
+// decodeUTF16BOM decodes UTF-16 in the byte order
+// given by its byte order mark, which is dropped,
+// or big-endian if there isn't one.
+func decodeUTF16BOM(data []byte) ([]rune, error) {
+	switch {
+	case len(data) >= 2 && data[0] == 0xff && data[1] == 0xfe:
+		return decodeUTF16(binary.LittleEndian)(data[2:])
+	case len(data) >= 2 && data[0] == 0xfe && data[1] == 0xff:
+		return decodeUTF16(binary.BigEndian)(data[2:])
+	}
+
+	return decodeUTF16(binary.BigEndian)(data)
+}
+
+// encodeUTF16BOM encodes big-endian UTF-16, with
+// a byte order mark.
+func encodeUTF16BOM(text []rune) ([]byte, error) {
+	data, err := encodeUTF16(binary.BigEndian)(text)
+	return append([]byte{0xfe, 0xff}, data...), err
+}
+
+func decodeLatin1(data []byte) ([]rune, error) {
+	text := make([]rune, len(data))
+	for i, b := range data {
+		text[i] = rune(b)
+	}
+
+	return text, nil
+}
+
+func encodeLatin1(text []rune) ([]byte, error) {
+	data := make([]byte, len(text))
+	for i, r := range text {
+		if r > 0xff {
+			return nil, fmt.Errorf("%q can't be encoded", r)
+		}
+
+		data[i] = byte(r)
+	}
+
+	return data, nil
+}
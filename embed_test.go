@@ -0,0 +1,344 @@
+package main
+
+import (
+	"compress/gzip"
+	"go/build/constraint"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/SlyMarbo/embed/embed"
+)
+
+func TestStdin(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdin := os.Stdin
+	name := *stdinName
+	defer func() {
+		os.Stdin = stdin
+		*stdinName = name
+	}()
+
+	os.Stdin = r
+	*stdinName = "piped"
+	go func() {
+		io.WriteString(w, "data")
+		w.Close()
+	}()
+
+	inputs := Inputs([]Arg{{Path: "-"}})
+	if len(inputs) != 1 || inputs[0].Name != "piped" {
+		t.Fatalf("got inputs %v, want one named piped", inputs)
+	}
+
+	out := embed.NewOutput(embed.Options{Package: "p", Compression: embed.Compressors["gzip"], Level: gzip.BestCompression, Hashes: []string{"sha1"}, HashFormat: "hex"})
+	if _, err = embedInput(out, inputs[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := embed.Render(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"var piped_gz = []byte{",
+		"func piped() ([]byte, error) {",
+		"const piped_SHA1 = \"a17c9aaa61e80a1bf71d0d850af4e5baa9800bbd\"",
+		"const piped_Size = 4",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("got:\n%s\nwant it to contain %q", src, want)
+		}
+	}
+}
+
+func TestName(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "logo.png")
+	if err := os.WriteFile(name, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	inputs := Inputs([]Arg{{Path: name}})
+	if err := nameInputs(inputs, "logo", false); err != nil {
+		t.Fatal(err)
+	}
+
+	out := embed.NewOutput(embed.Options{Package: "p"})
+	if _, err := embedInput(out, inputs[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := embed.Render(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "var logo = []byte{"; !strings.Contains(string(src), want) {
+		t.Errorf("got:\n%s\nwant it to contain %q", src, want)
+	}
+
+	if err = nameInputs(inputs, "2logo", false); err == nil {
+		t.Error("-name 2logo succeeded, want an error")
+	}
+
+	// Several files can only be named with -map.
+
+	several := []Input{{Path: "a.txt"}, {Path: "b.txt"}, {Path: "c.txt"}}
+	if err = nameInputs(several, "asset", false); err == nil {
+		t.Error("-name with several files succeeded, want an error")
+	}
+
+	if err = nameInputs(several, "asset", true); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []string{"asset", "asset_2", "asset_3"} {
+		if got := several[i].Var; got != want {
+			t.Errorf("%s: got %s, want %s", several[i].Path, got, want)
+		}
+	}
+}
+
+func TestPackageName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string // Empty if invalid.
+	}{
+		{"assets", "assets"},
+		{"my-assets", "my_assets"},
+		{"v2", "v2"},
+		{"2fast", ""},
+		{"_assets", ""},
+		{"range", ""},
+		{"package", ""},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		got, err := packageName(test.name)
+		switch {
+		case test.want == "" && err == nil:
+			t.Errorf("packageName(%q) = %q, want an error", test.name, got)
+		case test.want != "" && err != nil:
+			t.Errorf("packageName(%q): %v", test.name, err)
+		case got != test.want:
+			t.Errorf("packageName(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestGroups(t *testing.T) {
+	var g groupList
+	for _, value := range []string{"css=a.css,b.css", "img=img", "css=c.css"} {
+		if err := g.Set(value); err != nil {
+			t.Fatalf("Set(%q): %v", value, err)
+		}
+	}
+
+	if got, want := g.String(), "css=a.css,b.css,c.css img=img"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	for _, value := range []string{"css", "css=", "9css=a.css", "range=a.css"} {
+		if err := g.Set(value); err == nil {
+			t.Errorf("Set(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestRename(t *testing.T) {
+	var r renameList
+	for _, value := range []string{"build/output/app.min.js=app.js", "./img/logo.png=logo.png"} {
+		if err := r.Set(value); err != nil {
+			t.Fatalf("Set(%q): %v", value, err)
+		}
+	}
+
+	for _, value := range []string{"app.js", "=app.js", "app.js=", "-=stdin", "build/output/app.min.js=other.js", "other.js=app.js"} {
+		if err := r.Set(value); err == nil {
+			t.Errorf("Set(%q) succeeded, want an error", value)
+		}
+	}
+
+	inputs := r.apply([]Input{
+		{Path: "build/output/app.min.js", Name: "build/output/app.min.js", Ident: "app.min.js"},
+		{Path: "img/logo.png", Name: "img/logo.png", Ident: "logo.png"},
+		{Path: "a.txt", Name: "a.txt", Ident: "a.txt"},
+	})
+
+	want := []Input{
+		{Path: "build/output/app.min.js", Name: "app.js", Ident: "app.js"},
+		{Path: "img/logo.png", Name: "logo.png", Ident: "logo.png"},
+		{Path: "a.txt", Name: "a.txt", Ident: "a.txt"},
+	}
+
+	if !reflect.DeepEqual(inputs, want) {
+		t.Errorf("got %v, want %v", inputs, want)
+	}
+}
+
+func TestRenameCollision(t *testing.T) {
+	var r renameList
+	if err := r.Set("b.txt=a.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	n := failures
+	defer func() { failures = n }()
+
+	inputs := r.apply([]Input{{Path: "a.txt", Name: "a.txt"}, {Path: "b.txt", Name: "b.txt"}})
+	if len(inputs) != 1 || inputs[0].Path != "a.txt" || failures != n+1 {
+		t.Errorf("got %v with %d failures, want only a.txt with 1", inputs, failures-n)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	paths := []string{"a.txt", "dir/b.txt", `odd\name`, "new\nline"}
+	sums := [][]byte{{0x01, 0x23}, {0x45, 0x67}, {0x89, 0xab}, {0xcd, 0xef}}
+	want := "0123  a.txt\n4567  dir/b.txt\n\\89ab  odd\\\\name\n\\cdef  new\\nline\n"
+	if got := string(Manifest(paths, sums)); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMaxFileSize(t *testing.T) {
+	max := *maxFileSize
+	defer func() { *maxFileSize = max }()
+
+	name := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(name, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		max int64
+		ok  bool
+	}{{0, true}, {10, true}, {9, false}} {
+		*maxFileSize = test.max
+		if err := checkSize(Input{Path: name}); (err == nil) != test.ok {
+			t.Errorf("-max-file-size %d: got %v", test.max, err)
+		}
+
+		if test.max == 0 {
+			continue
+		}
+
+		_, err := io.ReadAll(&limitReader{io.NopCloser(strings.NewReader("0123456789")), test.max})
+		if (err == nil) != test.ok {
+			t.Errorf("-max-file-size %d: reading standard input got %v", test.max, err)
+		}
+	}
+}
+
+func TestDuplicateInputs(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(name, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.Chdir(wd)
+
+	inputs := Inputs([]Arg{{Path: "a.txt"}, {Path: "a.txt"}, {Path: name}, {Path: "*.txt"}, {Path: "."}})
+	if len(inputs) != 1 || inputs[0].Path != "a.txt" {
+		t.Errorf("got %v, want only a.txt", inputs)
+	}
+}
+
+func TestFileTags(t *testing.T) {
+	tests := []struct {
+		name string
+		want string // Empty if unconstrained.
+	}{
+		{"logo_linux_amd64.png", "linux && amd64"},
+		{"dir/icon_windows.ico", "windows"},
+		{"data_arm64.bin", "arm64"},
+		{"data_linux_test.txt", "linux"},
+		{"logo_amd64_linux.png", "linux"},
+		{"logo_linux_amd64.tar.gz", "linux && amd64"},
+		{"linux.png", ""},
+		{"linux_amd64/logo.png", ""},
+		{"logo_other.png", ""},
+		{"logo.png", ""},
+	}
+
+	for _, test := range tests {
+		got := ""
+		if expr := fileTags(test.name); expr != nil {
+			got = expr.String()
+		}
+
+		if got != test.want {
+			t.Errorf("fileTags(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+
+	tags, err := constraint.Parse("//go:build foo || bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := withFileTags(tags, "logo_linux.png").String(), "(foo || bar) && linux"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	auto := *noAutoTags
+	defer func() { *noAutoTags = auto }()
+
+	*noAutoTags = true
+	if got := withFileTags(nil, "logo_linux.png"); got != nil {
+		t.Errorf("with -no-auto-tags, got %v", got)
+	}
+
+	// The output is renamed so that the go command
+	// doesn't constrain it by its name either.
+
+	dir := *outdir
+	defer func() { *outdir = dir }()
+
+	for _, test := range []struct {
+		outdir string
+		name   string
+		want   string
+	}{
+		{"", "img/logo_linux_amd64.png", "logo_linux-amd64.png.go"},
+		{"", "icon_windows.ico", "icon-windows.ico.go"},
+		{"", "data_linux_test.tar.gz", "data_linux-test.tar.gz.go"},
+		{"", "logo.png", "logo.png.go"},
+		{"", "linux.png", "linux.png.go"},
+		{"gen", "img/logo_linux.png", filepath.Join("gen", "img_logo-linux.png.go")},
+	} {
+		*outdir = test.outdir
+		name := outputName(Input{Name: test.name})
+		if name != test.want {
+			t.Errorf("outputName(%q) = %q, want %q", test.name, name, test.want)
+		}
+
+		if expr := fileTags(name); expr != nil {
+			t.Errorf("%s is constrained by %v", name, expr)
+		}
+	}
+
+	*outdir = ""
+	*noAutoTags = false
+	if got, want := outputName(Input{Name: "logo_linux_amd64.png"}), "logo_linux_amd64.png.go"; got != want {
+		t.Errorf("without -no-auto-tags, got %q, want %q", got, want)
+	}
+}
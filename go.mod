@@ -0,0 +1,3 @@
+module github.com/SlyMarbo/embed
+
+go 1.16
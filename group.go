@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"strings"
+)
+
+// PackagePlaceholder is replaced in -o with the
+// package of each group given with -group.
+const PackagePlaceholder = "{pkg}"
+
+// group is a package given with -group, and the
+// paths of the files embedded in it.
+type group struct {
+	pkg   string
+	paths []string
+}
+
+// groupList is a flag that may be repeated, each
+// time giving a package and its files as
+// pkg=file1,file2. Files given for the same
+// package more than once are combined.
+type groupList []group
+
+func (g *groupList) String() string {
+	var s []string
+	for _, group := range *g {
+		s = append(s, group.pkg+"="+strings.Join(group.paths, ","))
+	}
+
+	return strings.Join(s, " ")
+}
+
+func (g *groupList) Set(value string) error {
+	i := strings.Index(value, "=")
+	if i < 0 {
+		return errors.New("must be given as pkg=file1,file2")
+	}
+
+	pkg, err := packageName(value[:i])
+	if err != nil {
+		return err
+	}
+
+	var paths []string
+	for _, path := range strings.Split(value[i+1:], ",") {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+
+	if len(paths) == 0 {
+		return errors.New("no files given for " + pkg)
+	}
+
+	for i := range *g {
+		if (*g)[i].pkg == pkg {
+			(*g)[i].paths = append((*g)[i].paths, paths...)
+			return nil
+		}
+	}
+
+	*g = append(*g, group{pkg: pkg, paths: paths})
+	return nil
+}
+
+// groups holds the packages given with -group.
+var groups groupList
+
+func init() {
+	flag.Var(&groups, "group", "Embed these files in their own package, given as pkg=file1,file2, written to -o with "+PackagePlaceholder+" replaced by the package (may be repeated)")
+}
+
+// args returns the arguments for the files in g.
+func (g group) args() []Arg {
+	args := make([]Arg, len(g.paths))
+	for i, path := range g.paths {
+		args[i] = Arg{Path: path, Origin: "-group " + g.pkg}
+	}
+
+	return args
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// patterns is a flag that may be repeated.
+type patterns []string
+
+func (p *patterns) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *patterns) Set(pattern string) error {
+	*p = append(*p, pattern)
+	return nil
+}
+
+// excludes holds the patterns given with -exclude.
+var excludes patterns
+
+func init() {
+	flag.Var(&excludes, "exclude", "Skip files matching this pattern when walking directories (may be repeated)")
+}
+
+// IgnoreFile is the name of the file listing the
+// files to skip when walking a directory.
+const IgnoreFile = ".embedignore"
+
+// Ignore is a list of patterns matching files
+// to skip, in the style of .gitignore.
+//
+// Patterns without a slash match the name of a
+// file or directory anywhere, while those with a
+// slash match its path from the root. A leading
+// ! re-includes files matched by earlier patterns,
+// and a trailing slash only matches directories.
+// As well as the usual glob patterns, ** matches
+// any number of directories.
+type Ignore []ignorePattern
+
+type ignorePattern struct {
+	elems    []string // Pattern for each element of the path.
+	anchored bool     // Whether the pattern matches the whole path.
+	dirOnly  bool     // Whether the pattern only matches directories.
+	negate   bool     // Whether the pattern re-includes files.
+}
+
+// Add adds a pattern to ig. Blank lines and
+// comments are ignored.
+func (ig *Ignore) Add(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return
+	}
+
+	var p ignorePattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+
+	p.anchored = strings.Contains(line, "/")
+	p.elems = strings.Split(strings.TrimPrefix(line, "/"), "/")
+	*ig = append(*ig, p)
+}
+
+// Match reports whether the file with the given
+// slash-separated path relative to the root is
+// ignored. The last pattern to match decides.
+func (ig Ignore) Match(rel string, isDir bool) bool {
+	elems := strings.Split(rel, "/")
+	ignored := false
+	for _, p := range ig {
+		if p.dirOnly && !isDir {
+			continue
+		}
+
+		var ok bool
+		if p.anchored {
+			ok, _ = match(p.elems, elems)
+		} else {
+			ok, _ = path.Match(p.elems[0], elems[len(elems)-1])
+		}
+
+		if ok {
+			ignored = !p.negate
+		}
+	}
+
+	return ignored
+}
+
+// loadIgnore returns the patterns given with
+// -exclude, followed by those in the ignore file
+// in root, if there is one.
+func loadIgnore(root string) (Ignore, error) {
+	var ig Ignore
+	for _, pattern := range excludes {
+		ig.Add(pattern)
+	}
+
+	f, err := os.Open(filepath.Join(root, IgnoreFile))
+	if os.IsNotExist(err) {
+		return ig, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		ig.Add(s.Text())
+	}
+
+	return ig, s.Err()
+}
@@ -0,0 +1,490 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Arg is a path naming files to embed.
+type Arg struct {
+	Path   string
+	Origin string // Where the path was listed, if not an argument.
+}
+
+// Args returns the command-line arguments, followed
+// by the paths listed in the file named by -list.
+func Args() ([]Arg, error) {
+	var args []Arg
+	for _, path := range flag.Args() {
+		args = append(args, Arg{Path: path})
+	}
+
+	if *list == "" {
+		return args, nil
+	}
+
+	f, err := os.Open(*list)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for line := 1; s.Scan(); line++ {
+		path := strings.TrimSpace(s.Text())
+		if path == "" || strings.HasPrefix(path, "#") {
+			continue
+		}
+
+		args = append(args, Arg{Path: path, Origin: fmt.Sprintf("%s:%d", *list, line)})
+	}
+
+	return args, s.Err()
+}
+
+// Input is a file to be embedded.
+type Input struct {
+	Path   string // Path to the file, or "-" for standard input.
+	Name   string // Name of the file in generated code.
+	Ident  string // Name from which identifiers are derived.
+	Var    string // Identifier given with -name, used as is.
+	Origin string // Where the file was listed, if at all.
+}
+
+// filename returns the name of the
+// input's file, for use in messages.
+func (in Input) filename() string {
+	if in.Path == "-" {
+		return "standard input"
+	}
+
+	return in.Path
+}
+
+// errorf prints an error message to standard
+// error, prefixed with the program's name.
+func errorf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "%s: %s\n", filepath.Base(os.Args[0]), fmt.Sprintf(format, args...))
+}
+
+// failures is the number of files that
+// couldn't be embedded.
+var failures int
+
+// report prints err, prefixed with origin
+// if it isn't empty, and counts the failure.
+// With -strict, it exits instead.
+func report(origin string, err error) {
+	if origin != "" {
+		errorf("%s: %v", origin, err)
+	} else {
+		errorf("%v", err)
+	}
+
+	failures++
+	if *strict {
+		os.Exit(1)
+	}
+}
+
+// summarise exits with a summary if any of
+// the total files couldn't be embedded.
+func summarise(total int) {
+	if failures == 0 {
+		return
+	}
+
+	errorf("%d of %d files failed", failures, total)
+	os.Exit(1)
+}
+
+// Inputs expands the arguments into the files to
+// embed. Identifiers for files are derived from
+// their base name. Directories are walked, and the
+// identifiers for the files in them are derived
+// from their path relative to the directory's
+// parent, so that files with the same base name
+// in different directories don't collide. Files
+// matching -exclude, or the patterns in the
+// directory's .embedignore, are skipped, as are
+// symlinks unless -follow-symlinks is set.
+//
+// The path "-" reads standard input, which must
+// be named with -name.
+//
+// Arguments containing glob patterns are expanded
+// first, as described by Glob. Files given more
+// than once are only embedded once.
+//
+// Errors are reported and the offending argument
+// skipped.
+func Inputs(args []Arg) []Input {
+	var inputs []Input
+	for _, arg := range expand(args) {
+		if arg.Path == "-" {
+			if *stdinName == "" {
+				report(arg.Origin, errors.New("reading standard input requires -name"))
+				continue
+			}
+
+			inputs = append(inputs, Input{Path: arg.Path, Name: *stdinName, Ident: *stdinName, Origin: arg.Origin})
+			continue
+		}
+
+		info, err := os.Stat(arg.Path)
+		if err != nil {
+			report(arg.Origin, err)
+			continue
+		}
+
+		if !info.IsDir() {
+			inputs = append(inputs, Input{Path: arg.Path, Name: arg.Path, Ident: filepath.Base(arg.Path), Origin: arg.Origin})
+			continue
+		}
+
+		root := arg.Path
+		ignore, err := loadIgnore(root)
+		if err != nil {
+			report(arg.Origin, err)
+			continue
+		}
+
+		w := walker{arg: arg, base: filepath.Base(root), ignore: ignore, active: make(map[string]bool)}
+		if err = w.walk(root, ""); err != nil {
+			report(arg.Origin, err)
+		}
+
+		inputs = append(inputs, w.inputs...)
+	}
+
+	return dedupe(inputs)
+}
+
+// dedupe returns inputs without the files given
+// more than once, such as by overlapping patterns,
+// keeping the first. With -strict, they're
+// reported instead.
+func dedupe(inputs []Input) []Input {
+	seen := make(map[string]bool)
+	kept := inputs[:0]
+	for _, in := range inputs {
+		path := in.Path
+		if path != "-" {
+			if abs, err := filepath.Abs(path); err == nil {
+				path = abs
+			}
+		}
+
+		if !seen[path] {
+			seen[path] = true
+			kept = append(kept, in)
+			continue
+		}
+
+		if *strict {
+			report(in.Origin, fmt.Errorf("%s is given more than once", in.filename()))
+		} else if *verbose {
+			fmt.Fprintf(os.Stderr, "%s: skipping duplicate\n", in.filename())
+		}
+	}
+
+	return kept
+}
+
+// walker collects the files in a directory
+// given as an argument.
+type walker struct {
+	arg    Arg
+	base   string
+	ignore Ignore
+	inputs []Input
+
+	// active holds the resolved paths of the
+	// directories being walked, so that symlinks
+	// back to them aren't followed forever.
+	active map[string]bool
+}
+
+// walk adds the files in dir, whose path relative
+// to the argument is rel. Symlinks are skipped,
+// unless -follow-symlinks is set, in which case
+// symlinked directories are walked in turn.
+func (w *walker) walk(dir, rel string) error {
+	resolved, err := resolve(dir)
+	if err != nil {
+		return err
+	}
+
+	w.active[resolved] = true
+	defer delete(w.active, resolved)
+
+	// A symlink is walked from its target, as
+	// WalkDir doesn't follow it, but the files
+	// are still named by their path through it.
+
+	root := dir
+	if rel != "" {
+		root = resolved
+	}
+
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path == root {
+			return nil
+		}
+
+		name, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		path = filepath.Join(dir, name)
+		name = filepath.Join(rel, name)
+		mode := d.Type()
+		link := mode&fs.ModeSymlink != 0
+		if link {
+			if !*followSymlinks {
+				if *verbose {
+					fmt.Fprintf(os.Stderr, "%s: skipping symlink\n", path)
+				}
+
+				return nil
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+
+			mode = info.Mode()
+		}
+
+		if w.ignore.Match(filepath.ToSlash(name), mode.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if mode.IsDir() {
+			if !*recurse {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+
+				return nil
+			}
+
+			if !link {
+				return nil
+			}
+
+			target, err := resolve(path)
+			if err != nil {
+				return err
+			}
+
+			if w.active[target] {
+				if *verbose {
+					fmt.Fprintf(os.Stderr, "%s: skipping symlink cycle\n", path)
+				}
+
+				return nil
+			}
+
+			return w.walk(path, name)
+		}
+
+		if !mode.IsRegular() || name == IgnoreFile || name == CacheFile {
+			return nil
+		}
+
+		w.inputs = append(w.inputs, Input{Path: path, Name: path, Ident: filepath.Join(w.base, name), Origin: w.arg.Origin})
+		return nil
+	})
+}
+
+// resolve returns the absolute path of
+// the file at path, after any symlinks.
+func resolve(path string) (string, error) {
+	path, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Abs(path)
+}
+
+// nameInputs gives inputs the identifier name from
+// -name. Several inputs can only be named when
+// they're indexed by -map, which is how they're
+// looked up, and are numbered in order, as in
+// name, name_2 and so on.
+func nameInputs(inputs []Input, name string, indexed bool) error {
+	if len(inputs) != 1 && !indexed {
+		return errors.New("-name can only be used with standard input, a single file or -map")
+	}
+
+	if !token.IsIdentifier(name) {
+		return fmt.Errorf("invalid -name: %q is not a valid identifier", name)
+	}
+
+	for i := range inputs {
+		inputs[i].Var = name
+		if i > 0 {
+			inputs[i].Var = fmt.Sprintf("%s_%d", name, i+1)
+		}
+	}
+
+	return nil
+}
+
+// readsStdin reports whether any of the inputs
+// is standard input.
+func readsStdin(inputs []Input) bool {
+	for _, in := range inputs {
+		if in.Path == "-" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// expand replaces the arguments that contain glob
+// patterns with the paths that match them. Paths
+// that exist are taken literally, even if they
+// contain glob metacharacters.
+func expand(args []Arg) []Arg {
+	var expanded []Arg
+	for _, arg := range args {
+		if arg.Path == "-" || !hasMeta(arg.Path) {
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		if _, err := os.Lstat(arg.Path); err == nil {
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		matches, err := Glob(arg.Path)
+		if err != nil {
+			report(arg.Origin, err)
+			continue
+		}
+
+		if len(matches) == 0 {
+			report(arg.Origin, fmt.Errorf("no files match %s", arg.Path))
+			continue
+		}
+
+		for _, path := range matches {
+			expanded = append(expanded, Arg{Path: path, Origin: arg.Origin})
+		}
+	}
+
+	return expanded
+}
+
+// hasMeta reports whether path contains any of
+// the special characters used in glob patterns.
+func hasMeta(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// Glob returns the paths matching pattern, as
+// filepath.Glob does, except that a ** element
+// matches any number of directories. Only
+// regular files match patterns containing **.
+func Glob(pattern string) ([]string, error) {
+	if !strings.Contains(pattern, "**") {
+		return filepath.Glob(pattern)
+	}
+
+	// The directory holding the matches is
+	// walked, starting at the elements before
+	// the first pattern.
+
+	elems := strings.Split(filepath.ToSlash(pattern), "/")
+	n := 0
+	for n < len(elems) && !hasMeta(elems[n]) {
+		n++
+	}
+
+	root := strings.Join(elems[:n], "/")
+	switch {
+	case n == 0:
+		root = "."
+	case root == "":
+		root = "/"
+	}
+
+	var matches []string
+	root = filepath.FromSlash(root)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		ok, err := match(elems[n:], strings.Split(filepath.ToSlash(rel), "/"))
+		if ok {
+			matches = append(matches, path)
+		}
+
+		return err
+	})
+
+	return matches, err
+}
+
+// match reports whether the elements of a path
+// match those of a pattern, where ** matches any
+// number of elements.
+func match(pattern, elems []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(elems); i++ {
+				if ok, err := match(pattern[1:], elems[i:]); ok || err != nil {
+					return ok, err
+				}
+			}
+
+			return false, nil
+		}
+
+		if len(elems) == 0 {
+			return false, nil
+		}
+
+		if ok, err := path.Match(pattern[0], elems[0]); !ok || err != nil {
+			return false, err
+		}
+
+		pattern, elems = pattern[1:], elems[1:]
+	}
+
+	return len(elems) == 0, nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+// Manifest returns the checksum manifest for the
+// files embedded from the given paths, in the
+// format written by sha256sum and the like: each
+// file's hash, by the first algorithm embedded, in
+// hex, then two spaces and its path. Paths holding
+// a backslash or newline have them escaped, and
+// the line marked with a leading backslash, as
+// sha256sum does.
+func Manifest(paths []string, sums [][]byte) []byte {
+	var b strings.Builder
+	for i, path := range paths {
+		if strings.ContainsAny(path, "\\\n") {
+			b.WriteByte('\\')
+			path = strings.NewReplacer("\\", "\\\\", "\n", "\\n").Replace(path)
+		}
+
+		b.WriteString(hex.EncodeToString(sums[i]))
+		b.WriteString("  ")
+		b.WriteString(path)
+		b.WriteByte('\n')
+	}
+
+	return []byte(b.String())
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/SlyMarbo/embed/embed"
+)
+
+// parseCompression returns the format given by
+// -compress and -gzip, or nil if there isn't one.
+func parseCompression() (*embed.Compressor, error) {
+	name := strings.ToLower(strings.TrimSpace(*compressFlag))
+	if *gzipFlag {
+		if name != "none" && name != "gzip" {
+			return nil, fmt.Errorf("-gzip conflicts with -compress=%s", name)
+		}
+
+		name = "gzip"
+	}
+
+	if name == "none" {
+		return nil, nil
+	}
+
+	c, ok := embed.Compressors[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown compression %q (must be one of gzip, zlib, flate or none)", name)
+	}
+
+	return c, nil
+}
+
+// parseHashes returns the names of the hashes
+// to embed, given by -hash, -sha1 and -sha256,
+// in the order they were requested.
+func parseHashes() ([]string, error) {
+	var algorithms []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			algorithms = append(algorithms, name)
+		}
+	}
+
+	if *hashList != "" {
+		for _, name := range strings.Split(*hashList, ",") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if _, ok := embed.Hashes[name]; !ok {
+				names := make([]string, 0, len(embed.Hashes))
+				for name := range embed.Hashes {
+					names = append(names, name)
+				}
+
+				sort.Strings(names)
+				return nil, fmt.Errorf("unknown hash %q (must be one of %s)", name, strings.Join(names, ", "))
+			}
+
+			add(name)
+		}
+	}
+
+	if *sha {
+		add("sha1")
+	}
+
+	if *sha2 {
+		add("sha256")
+	}
+
+	return algorithms, nil
+}
+
+// parseTranscode returns the encodings given by
+// -transcode, as from:to.
+func parseTranscode() (from, to *embed.Charset, err error) {
+	i := strings.Index(*transcode, ":")
+	if i < 0 {
+		return nil, nil, fmt.Errorf("%q must be given as from:to", *transcode)
+	}
+
+	if from, err = charset((*transcode)[:i]); err != nil {
+		return nil, nil, err
+	}
+
+	if to, err = charset((*transcode)[i+1:]); err != nil {
+		return nil, nil, err
+	}
+
+	return from, to, nil
+}
+
+// charset returns the named encoding.
+func charset(name string) (*embed.Charset, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	c, ok := embed.Charsets[name]
+	if !ok {
+		names := make([]string, 0, len(embed.Charsets))
+		for name := range embed.Charsets {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown encoding %q (must be one of %s)", name, strings.Join(names, ", "))
+	}
+
+	return c, nil
+}
+
+// applyFlags sets the options given directly
+// by the flags, once they have been checked.
+// The rest are set as they're parsed.
+func applyFlags(opts *embed.Options) {
+	opts.Package = *pkg
+	opts.Level = *level
+	opts.SmartCompress = *smartCompress
+	opts.MinCompressSize = *minCompress
+	opts.HashFirst = *hashFirst
+	opts.CRC32 = *crc
+	opts.HashFormat = *hashFormat
+	opts.String = *str
+	opts.Raw = *raw
+	opts.Lines = *lines
+	opts.Const = *constant
+	opts.Array = *array
+	opts.Chunk = *chunk
+	opts.ChunkString = *chunkString
+	opts.Width = *width
+	opts.Pretty = *pretty
+	opts.Blob = *blob
+	opts.Bundle = *bundle
+	opts.Prefix = *prefix
+	opts.TrimPrefix = *trim
+	opts.Export = *export
+	opts.ASCIINames = *asciiNames
+	opts.Reader = *reader
+	opts.BytesReader = *bytesReader
+	opts.CopyAccessor = *copyAccessor
+	opts.Funcs = *funcs
+	opts.GoEmbed = *goEmbed
+	opts.ModTime = *modtime
+	opts.Mode = *modeFlag
+	opts.Size = *sizes
+	opts.MIME = *mimeType
+	opts.Info = *info
+	opts.Docs = *docs
+	opts.NoComments = *noComments
+	opts.LineDirectives = *lineDirectives
+	opts.NormalizeEOL = *normalizeEOL
+	opts.Map = *index
+	opts.Assets = *assets
+	opts.HashedNames = *hashedNames
+	opts.Register = *register
+	opts.Struct = *structName
+	opts.Nested = *nested
+	opts.FS = *fsName
+	opts.HTTPFS = *httpFSName
+	opts.Marker = *marker
+	opts.Unformatted = !*gofmt
+
+	if *text != "" {
+		opts.TextExts = strings.Split(*text, ",")
+	}
+
+	if *generate {
+		opts.Generate = generateDirective()
+	}
+
+	if *showProgress {
+		opts.Progress = progress.add
+	}
+}
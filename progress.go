@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Progress tracks the data embedded so far, so
+// that -progress can report it.
+type Progress struct {
+	mu       sync.Mutex
+	bytes    int64
+	last     time.Time
+	width    int  // Width of the last update, to be overwritten.
+	terminal bool // Whether updates overwrite each other.
+}
+
+// progress is the progress reported by -progress.
+var progress Progress
+
+// start prepares p to report to stderr. Updates
+// overwrite each other on a terminal, and are
+// printed less often elsewhere.
+func (p *Progress) start() {
+	if info, err := os.Stderr.Stat(); err == nil {
+		p.terminal = info.Mode()&os.ModeCharDevice != 0
+	}
+}
+
+// add counts n bytes read from the named file,
+// printing an update if it's been long enough.
+func (p *Progress) add(name string, n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.bytes += int64(n)
+	interval := time.Second
+	if p.terminal {
+		interval = 100 * time.Millisecond
+	}
+
+	if now := time.Now(); now.Sub(p.last) >= interval {
+		p.last = now
+		p.print(fmt.Sprintf("%d bytes embedded, reading %s", p.bytes, name))
+	}
+}
+
+// finish prints the total.
+func (p *Progress) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.print(fmt.Sprintf("%d bytes embedded", p.bytes))
+	if p.terminal {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+func (p *Progress) print(line string) {
+	if !p.terminal {
+		fmt.Fprintln(os.Stderr, line)
+		return
+	}
+
+	pad := p.width - len(line)
+	if pad < 0 {
+		pad = 0
+	}
+
+	fmt.Fprintf(os.Stderr, "\r%s%*s", line, pad, "")
+	p.width = len(line)
+}
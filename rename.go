@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// renameList is a flag that may be repeated, each
+// time giving the path of a file and the logical
+// name to embed it under, as old=new.
+type renameList struct {
+	names map[string]string // Logical names by cleaned path.
+	used  map[string]bool   // Paths that matched a file.
+}
+
+func (r *renameList) String() string {
+	var s []string
+	for old, name := range r.names {
+		s = append(s, old+"="+name)
+	}
+
+	sort.Strings(s)
+	return strings.Join(s, " ")
+}
+
+func (r *renameList) Set(value string) error {
+	i := strings.Index(value, "=")
+	if i <= 0 || i == len(value)-1 {
+		return errors.New("must be given as old=new")
+	}
+
+	old := filepath.Clean(value[:i])
+	name := path.Clean(filepath.ToSlash(value[i+1:]))
+	if old == "-" {
+		return errors.New("standard input is named with -name")
+	}
+
+	if r.names == nil {
+		r.names = make(map[string]string)
+		r.used = make(map[string]bool)
+	}
+
+	if _, ok := r.names[old]; ok {
+		return fmt.Errorf("%s is renamed more than once", old)
+	}
+
+	for other, n := range r.names {
+		if n == name {
+			return fmt.Errorf("%s and %s are both renamed to %s", other, old, name)
+		}
+	}
+
+	r.names[old] = name
+	return nil
+}
+
+// renames holds the files given with -rename.
+var renames renameList
+
+func init() {
+	flag.Var(&renames, "rename", "Embed a file under another name, given as old=new, which is used for its identifiers and in -map, -fs and the like (may be repeated)")
+}
+
+// apply gives the inputs renamed with -rename their
+// logical names, deriving their identifiers from
+// them too. Files left with the same name as a
+// renamed one are reported, and all but the first
+// skipped.
+func (r *renameList) apply(inputs []Input) []Input {
+	if len(r.names) == 0 {
+		return inputs
+	}
+
+	renamed := make(map[string]bool)
+	for i, in := range inputs {
+		if in.Path == "-" {
+			continue
+		}
+
+		old := filepath.Clean(in.Path)
+		if name, ok := r.names[old]; ok {
+			r.used[old] = true
+			renamed[name] = true
+			inputs[i].Name = name
+			inputs[i].Ident = name
+		}
+	}
+
+	// The logical names must stay unique, so
+	// that the renamed files don't collide with
+	// any of the others.
+
+	kept := inputs[:0]
+	names := make(map[string]string)
+	for _, in := range inputs {
+		name := path.Clean(filepath.ToSlash(in.Name))
+		if other, ok := names[name]; ok && renamed[name] {
+			report(in.Origin, fmt.Errorf("%s and %s are both embedded as %s", other, in.filename(), name))
+			continue
+		}
+
+		names[name] = in.filename()
+		kept = append(kept, in)
+	}
+
+	return kept
+}
+
+// unused exits if any of the files given with
+// -rename didn't match any of the inputs, as the
+// name is probably mistyped.
+func (r *renameList) unused() {
+	var unused []string
+	for old := range r.names {
+		if !r.used[old] {
+			unused = append(unused, old)
+		}
+	}
+
+	if len(unused) == 0 {
+		return
+	}
+
+	sort.Strings(unused)
+	errorf("invalid -rename: %s matched no input", strings.Join(unused, ", "))
+	os.Exit(2)
+}